@@ -0,0 +1,184 @@
+package multichan
+
+import "context"
+
+// Delivery is an item read with R.ReadAck.
+// It must be acknowledged with Ack once it has been processed,
+// or else it is redelivered
+// (see Nack and AckTimeout).
+type Delivery[T any] struct {
+	Val    T
+	Offset int64 // the item's stream offset (see R.ReadOffset)
+
+	// Attempt is 1 the first time an item is delivered,
+	// 2 the first time it is redelivered,
+	// and so on.
+	Attempt int
+
+	r *reader[T]
+}
+
+// An unacked is an item read with ReadAck and not yet acknowledged.
+type unacked[T any] struct {
+	val     T
+	attempt int
+	queued  bool  // awaiting redelivery
+	timer   Timer // see AckTimeout
+}
+
+// ReadAck is like ReadOffset,
+// but the item it returns is redelivered
+// (by a later call to ReadAck on r,
+// or on another member of r's consumer group, see W.Group)
+// unless it is acknowledged with Delivery.Ack.
+// This allows the items in a work queue to survive a consumer's crash.
+//
+// Redelivered items take precedence over new ones.
+// If the multichan is closed and the last item has already been consumed,
+// ReadAck still blocks while any item read with it remains unacknowledged,
+// since that item may yet be redelivered.
+// The context argument may be nil.
+func (r *R[T]) ReadAck(ctx context.Context) (Delivery[T], bool) {
+	if !r.throttle(ctx) {
+		return Delivery[T]{}, false
+	}
+
+	r.w.mu.Lock()
+	defer r.w.mu.Unlock()
+
+	for r.waitAck(ctx) {
+		if len(r.redeliver) > 0 {
+			offset := r.redeliver[0]
+			r.redeliver = r.redeliver[1:]
+			u := r.unacked[offset]
+			u.queued = false
+			r.w.readHook(r.reader, item[T]{val: u.val, offset: offset})
+			return r.deliver(offset, u), true
+		}
+		if it, ok := r.next(); ok {
+			offset := it.offset
+			if u := r.unacked[offset]; u != nil && u.timer != nil {
+				// Re-reading an item (see SetPos) before acknowledging it.
+				u.timer.Stop()
+			}
+			if r.unacked == nil {
+				r.unacked = make(map[int64]*unacked[T])
+			}
+			u := &unacked[T]{val: it.val}
+			r.unacked[offset] = u
+			return r.deliver(offset, u), true
+		}
+	}
+	return Delivery[T]{}, false
+}
+
+// waitAck is like wait,
+// but also counts items awaiting redelivery as ready to read,
+// and keeps waiting after the multichan is closed
+// while there are unacknowledged items.
+// It returns false if r is disposed of or evicted (see EvictSlowest).
+// Callers must hold r.w.mu.
+func (r *R[T]) waitAck(ctx context.Context) bool {
+	for (ctx == nil || ctx.Err() == nil) && !(r.w.closed && len(r.unacked) == 0) && !r.disposed && !r.evicted && len(r.redeliver) == 0 && !r.ready() {
+		r.await(ctx)
+	}
+	return !r.disposed && !r.evicted && (len(r.redeliver) > 0 || r.ready())
+}
+
+// deliver records a new delivery attempt of u,
+// the item at the given offset,
+// and returns it as a Delivery.
+// Callers must hold r.w.mu.
+func (r *reader[T]) deliver(offset int64, u *unacked[T]) Delivery[T] {
+	u.attempt++
+	if r.ackTimeout > 0 {
+		attempt := u.attempt
+		u.timer = r.w.clock.AfterFunc(r.ackTimeout, func() {
+			r.w.mu.Lock()
+			defer r.w.mu.Unlock()
+			r.requeue(offset, attempt)
+		})
+	}
+	return Delivery[T]{Val: u.val, Offset: offset, Attempt: u.attempt, r: r}
+}
+
+// pending returns the unacknowledged item for the given delivery attempt,
+// or nil if that attempt has been acknowledged or superseded.
+// Callers must hold r.w.mu.
+func (r *reader[T]) pending(offset int64, attempt int) *unacked[T] {
+	u := r.unacked[offset]
+	if u == nil || u.queued || u.attempt != attempt {
+		return nil
+	}
+	return u
+}
+
+// requeue queues the item at the given offset for redelivery,
+// unless the given delivery attempt has been acknowledged or superseded.
+// If the item has been delivered the maximum number of times (see MaxAttempts),
+// it is sent to the multichan's dead-letter function instead.
+// It reports whether it requeued or dead-lettered the item.
+// Callers must hold r.w.mu.
+func (r *reader[T]) requeue(offset int64, attempt int) bool {
+	u := r.pending(offset, attempt)
+	if u == nil {
+		return false
+	}
+	if u.timer != nil {
+		u.timer.Stop()
+		u.timer = nil
+	}
+	if r.maxAttempts > 0 && u.attempt >= r.maxAttempts {
+		r.settle(offset)
+		r.w.sendDeadLetter(u.val, DroppedRejected)
+		return true
+	}
+	u.queued = true
+	r.redeliver = append(r.redeliver, offset)
+	r.notify()
+	return true
+}
+
+// Ack acknowledges d,
+// so that it will not be redelivered.
+// It reports false if it is too late for that:
+// d's ack timeout expired (see AckTimeout)
+// or it was already acknowledged or Nacked.
+func (d Delivery[T]) Ack() bool {
+	r := d.r
+	r.w.mu.Lock()
+	defer r.w.mu.Unlock()
+
+	u := r.pending(d.Offset, d.Attempt)
+	if u == nil {
+		return false
+	}
+	if u.timer != nil {
+		u.timer.Stop()
+	}
+	r.settle(d.Offset)
+	return true
+}
+
+// settle forgets the unacknowledged item at the given offset.
+// Callers must hold r.w.mu.
+func (r *reader[T]) settle(offset int64) {
+	delete(r.unacked, offset)
+	if r.w.closed && len(r.unacked) == 0 {
+		// Wake readers waiting in ReadAck for this.
+		r.notify()
+		r.checkDone()
+	}
+}
+
+// Nack rejects d,
+// queueing it for immediate redelivery
+// (or dead-lettering, see MaxAttempts).
+// It reports false if d's ack timeout already expired (see AckTimeout)
+// or d was already acknowledged or Nacked.
+func (d Delivery[T]) Nack() bool {
+	r := d.r
+	r.w.mu.Lock()
+	defer r.w.mu.Unlock()
+	return r.requeue(d.Offset, d.Attempt)
+}
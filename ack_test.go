@@ -0,0 +1,136 @@
+package multichan
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNack(t *testing.T) {
+	w := New[int]()
+	r := w.Reader()
+	defer r.Dispose()
+
+	w.Write(1)
+	w.Write(2)
+	w.Close()
+
+	d, ok := r.ReadAck(nil)
+	if !ok || d.Val != 1 || d.Attempt != 1 {
+		t.Fatalf("got %+v, %v; want 1 (attempt 1), true", d, ok)
+	}
+	if !d.Nack() {
+		t.Error("Nack failed")
+	}
+	if d.Ack() {
+		t.Error("Ack succeeded after Nack")
+	}
+
+	d, ok = r.ReadAck(nil)
+	if !ok || d.Val != 1 || d.Attempt != 2 {
+		t.Fatalf("got %+v, %v; want 1 (attempt 2), true", d, ok)
+	}
+	if !d.Ack() {
+		t.Error("Ack failed")
+	}
+
+	d, ok = r.ReadAck(nil)
+	if !ok || d.Val != 2 || d.Attempt != 1 {
+		t.Fatalf("got %+v, %v; want 2 (attempt 1), true", d, ok)
+	}
+	if !d.Ack() {
+		t.Error("Ack failed")
+	}
+
+	if d, ok := r.ReadAck(nil); ok {
+		t.Errorf("got %+v, want end of stream", d)
+	}
+}
+
+func TestReadAckEvicted(t *testing.T) {
+	w := New[int](Capacity(1, EvictSlowest))
+	r := w.Reader()
+
+	// Evict r.
+	w.Write(1)
+	w.Write(2)
+
+	done := make(chan bool)
+	go func() {
+		_, ok := r.ReadAck(nil)
+		done <- ok
+	}()
+
+	select {
+	case ok := <-done:
+		if ok {
+			t.Error("got an item from an evicted reader")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ReadAck did not return after eviction")
+	}
+}
+
+func TestAckTimeout(t *testing.T) {
+	w := New[int]()
+	g := w.Group(AckTimeout(10 * time.Millisecond))
+	defer g.Dispose()
+
+	r1, r2 := g.Reader(), g.Reader()
+	defer r1.Dispose()
+	defer r2.Dispose()
+
+	w.Write(1)
+	w.Close()
+
+	// r1 "crashes" without acknowledging the item.
+	d1, ok := r1.ReadAck(nil)
+	if !ok || d1.Val != 1 {
+		t.Fatalf("got %+v, %v; want 1, true", d1, ok)
+	}
+
+	// The item is redelivered to r2 after the timeout.
+	d2, ok := r2.ReadAck(nil)
+	if !ok || d2.Val != 1 || d2.Attempt != 2 {
+		t.Fatalf("got %+v, %v; want 1 (attempt 2), true", d2, ok)
+	}
+	if d1.Ack() {
+		t.Error("stale Ack succeeded")
+	}
+	if !d2.Ack() {
+		t.Error("Ack failed")
+	}
+
+	if d, ok := r1.ReadAck(nil); ok {
+		t.Errorf("got %+v, want end of stream", d)
+	}
+}
+
+func TestMaxAttempts(t *testing.T) {
+	var got []int
+	w := New[int](DeadLetter(func(val int, reason DropReason) {
+		if reason != DroppedRejected {
+			t.Errorf("got reason %v, want %v", reason, DroppedRejected)
+		}
+		got = append(got, val)
+	}))
+	r := w.Reader(MaxAttempts(2))
+	defer r.Dispose()
+
+	w.Write(1)
+	w.Close()
+
+	for i := 0; i < 2; i++ {
+		d, ok := r.ReadAck(nil)
+		if !ok {
+			t.Fatalf("unexpected end of stream on attempt %d", i+1)
+		}
+		d.Nack()
+	}
+	if d, ok := r.ReadAck(nil); ok {
+		t.Errorf("got %+v, want end of stream", d)
+	}
+	if !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("got %v, want [1]", got)
+	}
+}
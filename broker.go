@@ -0,0 +1,99 @@
+package multichan
+
+import (
+	"path"
+	"sort"
+)
+
+// Broker is a collection of multichans, called topics, identified by name.
+// A topic is created when it first gets a subscriber
+// and is closed and removed when its last subscriber goes away.
+//
+// A subscriber may also receive the items of every topic
+// whose name matches a pattern
+// (see SubscribePattern).
+//
+// Create one with NewBroker.
+type Broker[T any] struct {
+	topics   *topics[string, T]
+	patterns *topics[string, T] // multichans of items for pattern subscribers, keyed by pattern
+}
+
+// NewBroker produces a new Broker
+// whose topics are each created with New[T](opts...).
+// An Expvar option is ignored,
+// since topics come and go but a published name cannot be reused.
+func NewBroker[T any](opts ...Option) *Broker[T] {
+	return &Broker[T]{
+		topics:   newTopics[string, T](opts),
+		patterns: newTopics[string, T](opts),
+	}
+}
+
+// Publish writes an item to the named topic (see W.Write),
+// and for subscribers to patterns that match the topic name,
+// to each of those patterns (see SubscribePattern).
+// If the topic and the patterns have no subscribers,
+// the item is discarded.
+// If the broker is closed,
+// Publish returns ErrClosed.
+func (b *Broker[T]) Publish(topic string, val T) error {
+	if err := b.topics.publish(topic, val); err != nil {
+		return err
+	}
+	for _, pattern := range b.patterns.keys() {
+		if ok, _ := path.Match(pattern, topic); ok {
+			if err := b.patterns.publish(pattern, val); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Subscribe adds a new reader to the named topic,
+// creating the topic if necessary,
+// and returns it
+// (see W.Reader).
+// When the last of a topic's readers is disposed of,
+// the topic is closed and removed.
+//
+// If the broker is closed,
+// the reader is of an empty, closed multichan.
+func (b *Broker[T]) Subscribe(topic string, opts ...ReaderOption) *R[T] {
+	return b.topics.subscribe(topic, opts)
+}
+
+// SubscribePattern is like Subscribe,
+// but the new reader receives the items published to every topic
+// whose name matches the given pattern,
+// including topics created after the subscription.
+// The pattern syntax is that of path.Match,
+// so for example "orders.*" matches "orders.created" and "orders.shipped",
+// but "orders/*" does not match "orders/eu/shipped".
+// Readers with the same pattern share a multichan,
+// which is not among the broker's topics (see Topics).
+//
+// If the pattern is malformed,
+// SubscribePattern returns path.ErrBadPattern.
+func (b *Broker[T]) SubscribePattern(pattern string, opts ...ReaderOption) (*R[T], error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	return b.patterns.subscribe(pattern, opts), nil
+}
+
+// Topics returns the names of the broker's topics, in sorted order.
+func (b *Broker[T]) Topics() []string {
+	topics := b.topics.keys()
+	sort.Strings(topics)
+	return topics
+}
+
+// Close closes every topic (see W.Close),
+// along with the multichans of pattern subscribers.
+// Subsequent calls to Publish return ErrClosed.
+func (b *Broker[T]) Close() {
+	b.topics.close()
+	b.patterns.close()
+}
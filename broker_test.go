@@ -0,0 +1,133 @@
+package multichan
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBroker(t *testing.T) {
+	b := NewBroker[int]()
+	r1 := b.Subscribe("x")
+	r2 := b.Subscribe("x")
+	r3 := b.Subscribe("y")
+
+	if got, want := b.Topics(), []string{"x", "y"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got topics %v, want %v", got, want)
+	}
+
+	if err := b.Publish("x", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Publish("y", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Publish("z", 3); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, c := range []struct {
+		r    *R[int]
+		want int
+	}{{r1, 1}, {r2, 1}, {r3, 2}} {
+		if got, ok := c.r.NBRead(); !ok || got != c.want {
+			t.Errorf("case %d: got %d, %v; want %d, true", i+1, got, ok, c.want)
+		}
+	}
+
+	r1.Dispose()
+	r3.Dispose()
+	waitForTopics(t, b, []string{"x"})
+
+	if err := b.Publish("y", 4); err != nil {
+		t.Fatal(err)
+	}
+	if !r3.Closed() {
+		t.Error("topic y not closed")
+	}
+
+	r4 := b.Subscribe("y")
+	b.Publish("y", 5)
+	if got, _ := r4.NBRead(); got != 5 {
+		t.Errorf("got %d, want 5", got)
+	}
+
+	b.Close()
+	if err := b.Publish("x", 6); err != ErrClosed {
+		t.Errorf("got %v, want %v", err, ErrClosed)
+	}
+	if _, ok := r2.Read(nil); ok {
+		t.Error("read from closed topic")
+	}
+	if _, ok := b.Subscribe("x").Read(nil); ok {
+		t.Error("read from closed broker")
+	}
+}
+
+func waitForTopics(t *testing.T, b *Broker[int], want []string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		got := b.Topics()
+		if reflect.DeepEqual(got, want) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got topics %v, want %v", got, want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBrokerPattern(t *testing.T) {
+	b := NewBroker[string]()
+	orders, err := b.SubscribePattern("orders.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := b.SubscribePattern("*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := b.Subscribe("orders.created")
+
+	for _, topic := range []string{"orders.created", "users.created", "orders.shipped"} {
+		if err := b.Publish(topic, topic); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := b.Topics(), []string{"orders.created"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got topics %v, want %v", got, want)
+	}
+	b.Close()
+
+	cases := []struct {
+		r    *R[string]
+		want []string
+	}{
+		{orders, []string{"orders.created", "orders.shipped"}},
+		{all, []string{"orders.created", "users.created", "orders.shipped"}},
+		{created, []string{"orders.created"}},
+	}
+	for i, c := range cases {
+		got, err := c.r.Drain(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("case %d: got %v, want %v", i+1, got, c.want)
+		}
+	}
+
+	if _, err := NewBroker[int]().SubscribePattern("["); err == nil {
+		t.Error("got no error for malformed pattern")
+	}
+}
+
+func TestBrokerExpvar(t *testing.T) {
+	// Each topic would otherwise publish under the same name and panic.
+	b := NewBroker[int](Expvar("multichan_broker_test"))
+	defer b.Close()
+	b.Subscribe("x").Dispose()
+	b.Subscribe("y").Dispose()
+}
@@ -0,0 +1,69 @@
+package multichan
+
+import "context"
+
+// Chan returns a channel that receives the items read from r,
+// so that r can be used in a select statement.
+// A goroutine copies items from r to the channel
+// until the multichan is closed and the last item has been consumed,
+// or until the context is canceled,
+// at which point it closes the channel and disposes of r.
+// The context argument may be nil.
+func (r *R[T]) Chan(ctx context.Context) <-chan T {
+	var done <-chan struct{}
+	if ctx != nil {
+		done = ctx.Done()
+	}
+
+	ch := make(chan T)
+
+	go func() {
+		defer close(ch)
+		defer r.Dispose()
+
+		for {
+			val, ok := r.Read(ctx)
+			if !ok {
+				return
+			}
+			select {
+			case ch <- val:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+// CopyFrom writes to w each value received from ch
+// until ch is closed,
+// at which point it closes w and returns nil.
+// If the context is canceled first,
+// CopyFrom returns the context's error without closing w.
+// If a write fails (see WriteContext),
+// CopyFrom returns that error.
+// The context argument may be nil.
+func (w *W[T]) CopyFrom(ctx context.Context, ch <-chan T) error {
+	var done <-chan struct{}
+	if ctx != nil {
+		done = ctx.Done()
+	}
+
+	for {
+		select {
+		case val, ok := <-ch:
+			if !ok {
+				w.Close()
+				return nil
+			}
+			if err := w.WriteContext(ctx, val); err != nil {
+				return err
+			}
+
+		case <-done:
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package multichan
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestChan(t *testing.T) {
+	w := New[int]()
+	r := w.Reader()
+	ch := r.Chan(context.Background())
+
+	w.WriteBatch([]int{1, 2, 3})
+	w.Close()
+
+	var got []int
+	for val := range ch {
+		got = append(got, val)
+	}
+	if !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("got %v, want [1, 2, 3]", got)
+	}
+}
+
+func TestChanCancel(t *testing.T) {
+	w := New[int]()
+	r := w.Reader()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := r.Chan(ctx)
+
+	w.Write(1)
+	if got := <-ch; got != 1 {
+		t.Errorf("got %d, want 1", got)
+	}
+
+	cancel()
+	for range ch {
+	}
+}
+
+func TestCopyFrom(t *testing.T) {
+	w := New[int]()
+	r := w.Reader()
+
+	ch := make(chan int)
+	go func() {
+		for i := 1; i <= 3; i++ {
+			ch <- i
+		}
+		close(ch)
+	}()
+
+	if err := w.CopyFrom(context.Background(), ch); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := r.Drain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("got %v, want [1, 2, 3]", got)
+	}
+}
@@ -0,0 +1,166 @@
+package multichan
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// An OffsetStore persists the positions of named readers,
+// so that they can resume where they left off,
+// e.g. after the process restarts
+// (see W.ResumeReader).
+type OffsetStore interface {
+	// LoadOffset returns the stored position of the reader with the given name,
+	// or false if there is none.
+	LoadOffset(name string) (int64, bool, error)
+
+	// StoreOffset stores the position of the reader with the given name.
+	StoreOffset(name string, offset int64) error
+}
+
+// ResumeReader adds a new reader with the given name (see Name) to the multichan
+// and returns it.
+// The reader is positioned where the last reader with that name
+// committed its position to store (see R.Commit),
+// or as with Reader if there is no such position.
+// If the stored position is not in the range of items the multichan still retains,
+// ResumeReader returns an error wrapping ErrOffsetRange
+// (see ReaderAt).
+//
+// This is for consumers that must not miss or repeat items across restarts,
+// together with a multichan that retains items across restarts
+// (see Durable and Load).
+func (w *W[T]) ResumeReader(name string, store OffsetStore, opts ...ReaderOption) (*R[T], error) {
+	offset, ok, err := store.LoadOffset(name)
+	if err != nil {
+		return nil, fmt.Errorf("loading position of reader %s: %w", name, err)
+	}
+	opts = append(opts[:len(opts):len(opts)], Name(name))
+
+	var r *R[T]
+	if ok {
+		r, err = w.ReaderAt(offset, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("resuming reader %s at offset %d: %w", name, offset, err)
+		}
+	} else {
+		r, err = w.reader(opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	r.store = store
+	return r, nil
+}
+
+// Commit stores r's position (see Pos)
+// in the OffsetStore it was created with (see W.ResumeReader),
+// so that a reader resumed with the same name will start there.
+// If r was not created with ResumeReader,
+// Commit returns ErrNoOffsetStore.
+// Items read with ReadAck are counted as consumed
+// whether or not they have been acknowledged.
+//
+// Commit may be called after r is disposed,
+// to record its final position.
+func (r *R[T]) Commit() error {
+	if r.store == nil {
+		return ErrNoOffsetStore
+	}
+	r.w.mu.Lock()
+	name, pos := r.name, r.pos
+	r.w.mu.Unlock()
+
+	return r.store.StoreOffset(name, pos)
+}
+
+// A Token is an opaque representation of a reader's position in a stream
+// (see R.Token).
+// It can be stored by an application
+// and later used to recreate the reader at that position
+// (see W.ReaderAtToken).
+type Token string
+
+// tokenVersion begins the encoding of every Token.
+const tokenVersion = 1
+
+// Token returns r's position in the stream as a Token.
+func (r *R[T]) Token() Token {
+	buf := []byte{tokenVersion}
+	buf = binary.AppendVarint(buf, r.Pos())
+	return Token(base64.RawURLEncoding.EncodeToString(buf))
+}
+
+// ReaderAtToken is like ReaderAt,
+// but positions the new reader at the position represented by tok (see R.Token).
+// That must be in the range of items the multichan still retains;
+// otherwise ReaderAtToken returns ErrOffsetRange.
+// If tok is not valid, ReaderAtToken returns ErrBadToken.
+func (w *W[T]) ReaderAtToken(tok Token, opts ...ReaderOption) (*R[T], error) {
+	buf, err := base64.RawURLEncoding.DecodeString(string(tok))
+	if err != nil || len(buf) == 0 || buf[0] != tokenVersion {
+		return nil, ErrBadToken
+	}
+	offset, n := binary.Varint(buf[1:])
+	if n <= 0 || n != len(buf)-1 {
+		return nil, ErrBadToken
+	}
+	return w.ReaderAt(offset, opts...)
+}
+
+// DirOffsetStore is an OffsetStore
+// that keeps the position of each reader in a file in the named directory,
+// which must exist.
+type DirOffsetStore string
+
+var _ OffsetStore = DirOffsetStore("")
+
+// LoadOffset implements OffsetStore.LoadOffset.
+func (d DirOffsetStore) LoadOffset(name string) (int64, bool, error) {
+	data, err := os.ReadFile(d.path(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	offset, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("parsing position of reader %s: %w", name, err)
+	}
+	return offset, true, nil
+}
+
+// StoreOffset implements OffsetStore.StoreOffset.
+// It replaces the reader's file atomically,
+// so a crash cannot leave it partially written.
+func (d DirOffsetStore) StoreOffset(name string, offset int64) error {
+	f, err := os.CreateTemp(string(d), ".offset-*")
+	if err != nil {
+		return err
+	}
+	tmpname := f.Name()
+	_, err = fmt.Fprintf(f, "%d\n", offset)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmpname, d.path(name))
+	}
+	if err != nil {
+		os.Remove(tmpname)
+	}
+	return err
+}
+
+func (d DirOffsetStore) path(name string) string {
+	return filepath.Join(string(d), url.PathEscape(name)+".offset")
+}
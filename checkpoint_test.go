@@ -0,0 +1,81 @@
+package multichan
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestResumeReader(t *testing.T) {
+	store := DirOffsetStore(t.TempDir())
+
+	w := New[int](Retain(10))
+	w.WriteBatch([]int{1, 2, 3})
+
+	r, err := w.ResumeReader("a/b", store, FromEarliest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.NBRead()
+	r.NBRead()
+	r.Dispose()
+	if err := r.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err = w.ResumeReader("a/b", store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := r.NBRead(); got != 3 {
+		t.Errorf("got %d, want 3", got)
+	}
+	if got := w.ReaderInfo()[0].Name; got != "a/b" {
+		t.Errorf("got name %q, want a/b", got)
+	}
+
+	w = New[int]()
+	w.WriteBatch([]int{1, 2, 3})
+	if _, err := w.ResumeReader("a/b", store); !errors.Is(err, ErrOffsetRange) {
+		t.Errorf("got %v, want %v", err, ErrOffsetRange)
+	}
+
+	if err := w.Reader().Commit(); err != ErrNoOffsetStore {
+		t.Errorf("got %v, want %v", err, ErrNoOffsetStore)
+	}
+}
+
+func TestToken(t *testing.T) {
+	w := New[int](Retain(10))
+	w.WriteBatch([]int{1, 2, 3})
+
+	r := w.Reader(FromEarliest())
+	r.NBRead()
+	tok := r.Token()
+	r.NBRead()
+
+	r, err := w.ReaderAtToken(tok)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	got, err := r.Drain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	w = New[int]()
+	w.WriteBatch([]int{1, 2, 3})
+	if _, err := w.ReaderAtToken(tok); !errors.Is(err, ErrOffsetRange) {
+		t.Errorf("got %v, want %v", err, ErrOffsetRange)
+	}
+
+	for _, bad := range []Token{"", "x", tok + "A", "AQ"} {
+		if _, err := w.ReaderAtToken(bad); !errors.Is(err, ErrBadToken) {
+			t.Errorf("token %q: got %v, want %v", bad, err, ErrBadToken)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package multichan
+
+import "time"
+
+// Clock is the source of time for the time-based behavior of a multichan:
+// the expiration of items (see TTL),
+// time-based retention (see RetainFor),
+// and acknowledgment timeouts (see AckTimeout).
+// The default is the system clock.
+// Tests can substitute a fake one with UseClock,
+// such as multichantest.FakeClock.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// AfterFunc waits for the duration to elapse
+	// and then calls f in its own goroutine,
+	// like time.AfterFunc.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is the interface of the timers produced by a Clock.
+// It is satisfied by *time.Timer.
+type Timer interface {
+	// Stop prevents the timer from firing,
+	// reporting whether it was active.
+	Stop() bool
+
+	// Reset changes the timer to expire after the duration d,
+	// reporting whether it was active.
+	Reset(d time.Duration) bool
+}
+
+// UseClock causes a multichan to take the time from the given clock
+// instead of the system clock.
+func UseClock(clock Clock) Option {
+	return func(c *config) {
+		c.clock = clock
+	}
+}
+
+// systemClock is the default Clock.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
@@ -0,0 +1,89 @@
+package multichan
+
+import (
+	"sync"
+	"time"
+)
+
+// testClock is a Clock whose time changes only with advance,
+// for testing time-based behavior without sleeping.
+// (It is a pared-down multichantest.FakeClock,
+// which this package's tests cannot import.)
+type testClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers map[*testTimer]struct{}
+}
+
+func newTestClock() *testClock {
+	return &testClock{
+		now:    time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+		timers: make(map[*testTimer]struct{}),
+	}
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &testTimer{c: c, f: f, at: c.now.Add(d)}
+	c.timers[t] = struct{}{}
+	return t
+}
+
+// advance moves the clock forward by d,
+// synchronously calling the functions of the timers that expire,
+// in order.
+func (c *testClock) advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	for {
+		var next *testTimer
+		for t := range c.timers {
+			if !t.at.After(end) && (next == nil || t.at.Before(next.at)) {
+				next = t
+			}
+		}
+		if next == nil {
+			break
+		}
+		if next.at.After(c.now) {
+			c.now = next.at
+		}
+		delete(c.timers, next)
+
+		c.mu.Unlock()
+		next.f()
+		c.mu.Lock()
+	}
+	c.now = end
+	c.mu.Unlock()
+}
+
+type testTimer struct {
+	c  *testClock
+	f  func()
+	at time.Time
+}
+
+func (t *testTimer) Stop() bool {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+	_, ok := t.c.timers[t]
+	delete(t.c.timers, t)
+	return ok
+}
+
+func (t *testTimer) Reset(d time.Duration) bool {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+	_, ok := t.c.timers[t]
+	t.at = t.c.now.Add(d)
+	t.c.timers[t] = struct{}{}
+	return ok
+}
@@ -0,0 +1,47 @@
+package multichan
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+)
+
+// JSONCodec is a Codec that encodes values as JSON.
+type JSONCodec[T any] struct{}
+
+var _ Codec[int] = JSONCodec[int]{}
+
+// Encode implements Codec.Encode.
+func (JSONCodec[T]) Encode(val T) ([]byte, error) {
+	return json.Marshal(val)
+}
+
+// Decode implements Codec.Decode.
+func (JSONCodec[T]) Decode(data []byte) (T, error) {
+	var val T
+	err := json.Unmarshal(data, &val)
+	return val, err
+}
+
+// GobCodec is a Codec that encodes values with encoding/gob.
+// Each value is encoded separately,
+// together with a description of its type.
+type GobCodec[T any] struct{}
+
+var _ Codec[int] = GobCodec[int]{}
+
+// Encode implements Codec.Encode.
+func (GobCodec[T]) Encode(val T) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := gob.NewEncoder(buf).Encode(val); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode implements Codec.Decode.
+func (GobCodec[T]) Decode(data []byte) (T, error) {
+	var val T
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&val)
+	return val, err
+}
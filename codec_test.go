@@ -0,0 +1,30 @@
+package multichan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCodecs(t *testing.T) {
+	type point struct{ X, Y int }
+
+	codecs := map[string]Codec[point]{
+		"json": JSONCodec[point]{},
+		"gob":  GobCodec[point]{},
+	}
+	for name, codec := range codecs {
+		t.Run(name, func(t *testing.T) {
+			data, err := codec.Encode(point{1, 2})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := codec.Decode(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := (point{1, 2}); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package multichan
+
+import "context"
+
+// CloseOnDone arranges for w to be closed when the context is done,
+// with the context's cause (see context.Cause)
+// as the error (see CloseWithError).
+// This ties the lifetime of a multichan
+// to that of an operation or a server.
+//
+// Calling the returned stop function disassociates w from the context,
+// as with context.AfterFunc,
+// whose return value it shares.
+func (w *W[T]) CloseOnDone(ctx context.Context) (stop func() bool) {
+	return context.AfterFunc(ctx, func() {
+		w.CloseWithError(context.Cause(ctx))
+	})
+}
+
+// Context produces a context derived from the given parent
+// that is canceled when w is closed,
+// so that context-aware code can stop when the stream ends
+// (even if some readers have not yet consumed its last items).
+// The cause of the cancellation (see context.Cause)
+// is the error w was closed with (see CloseWithError),
+// or ErrClosed if there was none.
+//
+// As with context.WithCancel,
+// calling the returned function cancels the context
+// and releases its resources.
+func (w *W[T]) Context(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	ch := w.closedChan()
+
+	go func() {
+		select {
+		case <-ch:
+			w.mu.Lock()
+			err := w.err
+			w.mu.Unlock()
+			if err == nil {
+				err = ErrClosed
+			}
+			cancel(err)
+
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() { cancel(nil) }
+}
+
+// closedChan returns a channel that is closed when w is.
+func (w *W[T]) closedChan() <-chan struct{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closedCh == nil {
+		w.closedCh = make(chan struct{})
+		if w.closed {
+			close(w.closedCh)
+		}
+	}
+	return w.closedCh
+}
@@ -0,0 +1,86 @@
+package multichan
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCloseOnDone(t *testing.T) {
+	errTest := errors.New("test")
+
+	w := New[int]()
+	r := w.Reader()
+	ctx, cancel := context.WithCancelCause(context.Background())
+	w.CloseOnDone(ctx)
+
+	w.Write(1)
+	cancel(errTest)
+	if val, ok := r.Read(nil); !ok || val != 1 {
+		t.Errorf("got %d, %v; want 1, true", val, ok)
+	}
+	if _, ok := r.Read(nil); ok {
+		t.Error("got an item, want end of stream")
+	}
+	if err := r.Err(); err != errTest {
+		t.Errorf("got %v, want %v", err, errTest)
+	}
+
+	w = New[int]()
+	ctx, cancel = context.WithCancelCause(context.Background())
+	stop := w.CloseOnDone(ctx)
+	if !stop() {
+		t.Error("stop returned false")
+	}
+	cancel(nil)
+	if w.Closed() {
+		t.Error("multichan closed after stop")
+	}
+}
+
+func TestContext(t *testing.T) {
+	w := New[int]()
+	ctx, cancel := w.Context(context.Background())
+	defer cancel()
+
+	if ctx.Err() != nil {
+		t.Fatalf("context done before close: %v", ctx.Err())
+	}
+	w.Close()
+	<-ctx.Done()
+	if cause := context.Cause(ctx); cause != ErrClosed {
+		t.Errorf("got cause %v, want %v", cause, ErrClosed)
+	}
+
+	// Already closed, with an error.
+	errTest := errors.New("test")
+	w = New[int]()
+	w.CloseWithError(errTest)
+	ctx, cancel = w.Context(context.Background())
+	defer cancel()
+	<-ctx.Done()
+	if cause := context.Cause(ctx); cause != errTest {
+		t.Errorf("got cause %v, want %v", cause, errTest)
+	}
+
+	// Canceled before close.
+	w = New[int]()
+	ctx, cancel = w.Context(context.Background())
+	cancel()
+	if cause := context.Cause(ctx); cause != context.Canceled {
+		t.Errorf("got cause %v, want %v", cause, context.Canceled)
+	}
+}
+
+func TestContextAutoClose(t *testing.T) {
+	w := New[int](AutoClose())
+	r := w.Reader()
+	ctx, cancel := w.Context(context.Background())
+	defer cancel()
+
+	r.Dispose()
+	<-ctx.Done()
+	if cause := context.Cause(ctx); cause != ErrClosed {
+		t.Errorf("got cause %v, want %v", cause, ErrClosed)
+	}
+}
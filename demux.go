@@ -0,0 +1,58 @@
+package multichan
+
+// Demux is a writer that divides the items written to it
+// among separate multichans, called streams,
+// according to a key computed from each item.
+// A stream is created when it first gets a reader
+// and is closed and removed when its last reader goes away.
+//
+// Create one with NewDemux.
+type Demux[T any, K comparable] struct {
+	key     func(T) K
+	streams *topics[K, T]
+}
+
+// NewDemux produces a new Demux
+// that computes the key of each item with the given function
+// and whose streams are each created with New[T](opts...).
+// An Expvar option is ignored,
+// since streams come and go but a published name cannot be reused.
+func NewDemux[T any, K comparable](key func(T) K, opts ...Option) *Demux[T, K] {
+	return &Demux[T, K]{
+		key:     key,
+		streams: newTopics[K, T](opts),
+	}
+}
+
+// Write writes an item to the stream for its key (see W.Write).
+// If that stream has no readers,
+// the item is discarded.
+// If d is closed,
+// Write returns ErrClosed.
+func (d *Demux[T, K]) Write(val T) error {
+	return d.streams.publish(d.key(val), val)
+}
+
+// Stream adds a new reader to the stream for the given key,
+// creating the stream if necessary,
+// and returns it
+// (see W.Reader).
+// When the last of a stream's readers is disposed of,
+// the stream is closed and removed.
+//
+// If d is closed,
+// the reader is of an empty, closed multichan.
+func (d *Demux[T, K]) Stream(key K, opts ...ReaderOption) *R[T] {
+	return d.streams.subscribe(key, opts)
+}
+
+// Keys returns the keys of d's streams, in no particular order.
+func (d *Demux[T, K]) Keys() []K {
+	return d.streams.keys()
+}
+
+// Close closes every stream (see W.Close).
+// Subsequent calls to Write return ErrClosed.
+func (d *Demux[T, K]) Close() {
+	d.streams.close()
+}
@@ -0,0 +1,52 @@
+package multichan
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestDemux(t *testing.T) {
+	type event struct {
+		conn int
+		msg  string
+	}
+	d := NewDemux(func(e event) int { return e.conn })
+	r1 := d.Stream(1)
+	r2 := d.Stream(2)
+
+	for _, e := range []event{{1, "a"}, {2, "b"}, {3, "c"}, {1, "d"}} {
+		if err := d.Write(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys := d.Keys()
+	sort.Ints(keys)
+	if want := []int{1, 2}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("got keys %v, want %v", keys, want)
+	}
+
+	d.Close()
+
+	if err := d.Write(event{1, "e"}); err != ErrClosed {
+		t.Errorf("got %v, want %v", err, ErrClosed)
+	}
+
+	cases := []struct {
+		r    *R[event]
+		want []event
+	}{
+		{r1, []event{{1, "a"}, {1, "d"}}},
+		{r2, []event{{2, "b"}}},
+	}
+	for i, c := range cases {
+		got, err := c.r.Drain(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("case %d: got %v, want %v", i+1, got, c.want)
+		}
+	}
+}
@@ -1,9 +1,18 @@
 // Package multichan provides a one-to-many data channel.
 //
-// The source of some data creates a writer (type multichan.W)
+// The source of some data creates a writer (type multichan.W[T], for items of type T)
 // and supplies items to it one at a time with W.Write.
 //
 // Consumers of those items create readers with W.Reader
-// (producing a multichan.R)
+// (producing a multichan.R[T])
 // and read items with R.Read and R.NBRead.
+//
+// Blocked reads and writes wait only on channels,
+// and time-based behavior (see TTL, RetainFor, AckTimeout, and NewTicker)
+// uses timers rather than background goroutines,
+// so code built on multichans can be tested deterministically
+// in a testing/synctest bubble.
+// Alternatively,
+// a fake clock can be injected with UseClock
+// (see multichantest.FakeClock).
 package multichan
@@ -0,0 +1,361 @@
+package multichan
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+)
+
+// Durable is a multichan whose items are also appended to a log file,
+// so that the stream survives the restart of the process.
+// When the log is reopened with OpenDurable,
+// its items are written again to a new multichan,
+// at their original stream offsets,
+// and new items continue the stream from there.
+//
+// As with any multichan,
+// which of the replayed items remain available to readers
+// (e.g. with ReaderAt or FromEarliest)
+// depends on the retention policy (see Retain).
+// The log itself keeps every item ever written.
+//
+// Items are written to the log in the order of their stream offsets,
+// so writes that the multichan drops (see DropNewest and DedupConsecutive)
+// are not logged.
+// The time-to-live (see TTL) and priority (see Prioritized) of items are not logged;
+// replayed items never expire and have priority 0.
+// Errors written with WriteError are logged as their messages,
+// and replayed as new errors with the same messages.
+type Durable[T any] struct {
+	w *W[T]
+
+	// mu protects the log.
+	// It is not held while writing to w,
+	// which may block (see Capacity and Block),
+	// but it is acquired while w.mu is held (see append),
+	// so it must not be held while acquiring w.mu.
+	mu    sync.Mutex
+	f     *os.File
+	buf   *bufio.Writer
+	codec Codec[T]
+	err   error // the first error writing to the log, if any
+}
+
+// durableMagic begins every Durable log file.
+const durableMagic = "multichan log 1\n"
+
+// maxRecordSize bounds the size of a log record,
+// so that a corrupt length does not cause a huge allocation.
+const maxRecordSize = 1 << 30
+
+// OpenDurable opens the log file at path,
+// creating it if necessary,
+// and produces a Durable multichan from it,
+// created with New[T](opts...).
+// Items in the log are decoded with codec and replayed into the multichan,
+// and new items are encoded with it.
+//
+// If the log ends with a partial record,
+// as when the process exits in the middle of a write,
+// that record is removed.
+// If a record elsewhere in the log is corrupt,
+// OpenDurable returns an error,
+// leaving the log unchanged.
+func OpenDurable[T any](path string, codec Codec[T], opts ...Option) (*Durable[T], error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	d, err := openDurable(f, codec, opts)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+func openDurable[T any](f *os.File, codec Codec[T], opts []Option) (*Durable[T], error) {
+	w, err := NewChecked[T](opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() == 0 {
+		if _, err := io.WriteString(f, durableMagic); err != nil {
+			return nil, fmt.Errorf("writing log header: %w", err)
+		}
+	} else {
+		good, err := replay(f, codec, w)
+		if err != nil {
+			return nil, err
+		}
+		if good < fi.Size() {
+			if err := f.Truncate(good); err != nil {
+				return nil, fmt.Errorf("removing partial log record: %w", err)
+			}
+		}
+		if _, err := f.Seek(good, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+
+	d := &Durable[T]{
+		w:     w,
+		f:     f,
+		buf:   bufio.NewWriter(f),
+		codec: codec,
+	}
+	w.persist = d.append
+	return d, nil
+}
+
+// replay writes the items in the log file f to w.
+// It returns the length of the part of the file containing whole records.
+func replay[T any](f *os.File, codec Codec[T], w *W[T]) (int64, error) {
+	br := bufio.NewReader(f)
+	magic := make([]byte, len(durableMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != durableMagic {
+		return 0, fmt.Errorf("%s is not a multichan log", f.Name())
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	good := int64(len(durableMagic))
+	for {
+		data, n, err := readRecord(br)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			// The end of the log, or a partial record at the end.
+			return good, nil
+		}
+		if err == errChecksum {
+			if _, perr := br.Peek(1); perr == io.EOF {
+				// The last record, whose data was not all written.
+				return good, nil
+			}
+		}
+		if err != nil {
+			return 0, fmt.Errorf("reading log record %d at byte %d: %w", w.next, good, err)
+		}
+		switch data[0] {
+		case recordItem:
+			val, err := codec.Decode(data[1:])
+			if err != nil {
+				return 0, fmt.Errorf("decoding log record %d: %w", w.next, err)
+			}
+			w.add(item[T]{val: val}, 0)
+		case recordError:
+			w.add(item[T]{err: errors.New(string(data[1:]))}, 0)
+		default:
+			return 0, fmt.Errorf("log record %d has unknown kind %d", w.next, data[0])
+		}
+		good += n
+	}
+}
+
+// A log record is the length of its data as a uvarint,
+// then the data,
+// then the CRC-32 (IEEE) checksum of the data, little-endian.
+// The first byte of the data is the kind of the record,
+// and the rest is an encoded item or an error message.
+
+// The kinds of log records.
+const (
+	recordItem = iota
+	recordError
+)
+
+// errChecksum is the error from readRecord for a record whose data does not match its checksum.
+var errChecksum = errors.New("log record checksum mismatch")
+
+// readRecord reads a log record from br,
+// returning its data and its total length.
+func readRecord(br *bufio.Reader) ([]byte, int64, error) {
+	size, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, 0, err
+	}
+	if size == 0 {
+		return nil, 0, errors.New("empty log record")
+	}
+	if size > maxRecordSize {
+		return nil, 0, errors.New("log record too large")
+	}
+	data := make([]byte, size+4)
+	if _, err := io.ReadFull(br, data); err != nil {
+		return nil, 0, err
+	}
+	sum := binary.LittleEndian.Uint32(data[size:])
+	data = data[:size]
+	if crc32.ChecksumIEEE(data) != sum {
+		return nil, 0, errChecksum
+	}
+	n := len(binary.AppendUvarint(nil, size)) + len(data) + 4
+	return data, int64(n), nil
+}
+
+// append writes it to the log.
+// It is called (as w.persist) by d.w.add,
+// with d.w.mu held,
+// so records are written in the order of their stream offsets.
+func (d *Durable[T]) append(it item[T]) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.err != nil {
+		return
+	}
+	var data []byte
+	if it.err != nil {
+		data = append([]byte{recordError}, it.err.Error()...)
+	} else {
+		enc, err := d.codec.Encode(it.val)
+		if err != nil {
+			d.err = fmt.Errorf("encoding log record %d: %w", it.offset, err)
+			return
+		}
+		data = append([]byte{recordItem}, enc...)
+	}
+	var rec []byte
+	rec = binary.AppendUvarint(rec, uint64(len(data)))
+	rec = append(rec, data...)
+	rec = binary.LittleEndian.AppendUint32(rec, crc32.ChecksumIEEE(data))
+	if _, err := d.buf.Write(rec); err != nil {
+		d.err = fmt.Errorf("writing log: %w", err)
+	}
+}
+
+// Write adds an item to the multichan and to its log.
+// It is like W.Write,
+// but also returns an error if the item cannot be logged,
+// in which case the multichan is closed with that error
+// (see W.CloseWithError).
+// Items are written to the log file without waiting for it to reach stable storage;
+// for that, see Sync.
+func (d *Durable[T]) Write(val T) error {
+	return d.WriteContext(nil, val)
+}
+
+// WriteContext is like Write,
+// but if it blocks waiting for room in the queue
+// (see Capacity and Block),
+// it gives up when its context is canceled,
+// returning the context's error.
+// The context argument may be nil.
+func (d *Durable[T]) WriteContext(ctx context.Context, val T) error {
+	return d.logged(d.w.WriteContext(ctx, val))
+}
+
+// WriteBatch adds the given items to the multichan and to its log, in order.
+// It is like W.WriteBatch,
+// with the additional behavior of Write.
+func (d *Durable[T]) WriteBatch(vals []T) error {
+	return d.logged(d.w.WriteBatch(vals))
+}
+
+// WriteError adds a non-fatal error to the multichan and to its log.
+// It is like W.WriteError,
+// with the additional behavior of Write.
+func (d *Durable[T]) WriteError(err error) error {
+	return d.logged(d.w.WriteError(err))
+}
+
+// logged flushes the items just written to the log file,
+// closing the multichan if that fails.
+// The argument is the error, if any, from writing them to the multichan.
+func (d *Durable[T]) logged(err error) error {
+	d.mu.Lock()
+	if d.err == nil {
+		if ferr := d.buf.Flush(); ferr != nil {
+			d.err = fmt.Errorf("writing log: %w", ferr)
+		}
+	}
+	derr := d.err
+	d.mu.Unlock()
+
+	if derr != nil {
+		d.w.CloseWithError(derr)
+		return derr
+	}
+	return err
+}
+
+// Sync commits the log to stable storage,
+// so that its items survive a crash of the system
+// and not merely of the process.
+func (d *Durable[T]) Sync() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.err != nil {
+		return d.err
+	}
+	if d.f == nil {
+		return ErrClosed
+	}
+	return d.f.Sync()
+}
+
+// Close closes the multichan (see W.Close) and its log file.
+// Reopening the log with OpenDurable continues the stream.
+func (d *Durable[T]) Close() error {
+	return d.CloseWithError(nil)
+}
+
+// CloseWithError closes the multichan with the given error (see W.CloseWithError)
+// and closes its log file.
+// The error is not logged.
+func (d *Durable[T]) CloseWithError(err error) error {
+	// Closing the multichan first ends any write blocked waiting for room.
+	d.w.CloseWithError(err)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.f == nil {
+		return nil
+	}
+	ferr := d.buf.Flush()
+	if cerr := d.f.Close(); ferr == nil {
+		ferr = cerr
+	}
+	d.f = nil
+	return ferr
+}
+
+// Reader adds a new reader to the multichan and returns it.
+// See W.Reader.
+func (d *Durable[T]) Reader(opts ...ReaderOption) *R[T] {
+	return d.w.Reader(opts...)
+}
+
+// ReaderAt adds a new reader to the multichan,
+// positioned at the given stream offset,
+// and returns it.
+// See W.ReaderAt.
+func (d *Durable[T]) ReaderAt(offset int64, opts ...ReaderOption) (*R[T], error) {
+	return d.w.ReaderAt(offset, opts...)
+}
+
+// ResumeReader adds a new reader with the given name to the multichan,
+// positioned where the last reader with that name committed its position to store,
+// and returns it.
+// See W.ResumeReader.
+func (d *Durable[T]) ResumeReader(name string, store OffsetStore, opts ...ReaderOption) (*R[T], error) {
+	return d.w.ResumeReader(name, store, opts...)
+}
+
+// NumReaders tells how many readers the multichan has.
+func (d *Durable[T]) NumReaders() int {
+	return d.w.NumReaders()
+}
@@ -0,0 +1,271 @@
+package multichan
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDurable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+
+	d, err := OpenDurable[int](path, intCodec{}, Retain(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := d.Reader()
+	if err := d.WriteBatch([]int{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Write(4); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.Drain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 3, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if err := d.Write(5); err != ErrClosed {
+		t.Errorf("got %v, want %v", err, ErrClosed)
+	}
+
+	// Simulate a write interrupted by the exit of the process.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte{10, '1'}); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	d, err = OpenDurable[int](path, intCodec{}, Retain(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	r, err = d.ReaderAt(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Write(5); err != nil {
+		t.Fatal(err)
+	}
+	d.Close()
+
+	var offsets []int64
+	got = nil
+	for {
+		val, offset, ok := r.ReadOffset(nil)
+		if !ok {
+			break
+		}
+		got = append(got, val)
+		offsets = append(offsets, offset)
+	}
+	if want := []int{3, 4, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if want := []int64{2, 3, 4}; !reflect.DeepEqual(offsets, want) {
+		t.Errorf("got offsets %v, want %v", offsets, want)
+	}
+
+	d, err = OpenDurable[int](path, intCodec{}, Retain(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	r = d.Reader(FromEarliest())
+	d.Close()
+	got, err = r.Drain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 3, 4, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestDurableEncodeError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	d, err := OpenDurable[int](path, intCodec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	r := d.Reader()
+
+	if err := d.WriteBatch([]int{1, -1, 2}); err == nil {
+		t.Fatal("got no error")
+	}
+	if err := d.Write(3); err == nil {
+		t.Error("got no error")
+	}
+	got, err := r.Drain(nil)
+	if err == nil {
+		t.Error("got no error from reader")
+	}
+	if want := []int{1, -1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestDurableNotALog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	if err := os.WriteFile(path, []byte("hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenDurable[int](path, intCodec{}); err == nil {
+		t.Error("got no error")
+	}
+}
+
+func TestDurableCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+
+	d, err := OpenDurable[int](path, intCodec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.WriteBatch([]int{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Corrupt the data of the first record.
+	corrupt := bytes.Clone(data)
+	corrupt[len(durableMagic)+1] ^= 0xff
+	if err := os.WriteFile(path, corrupt, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenDurable[int](path, intCodec{}); err == nil {
+		t.Error("got no error")
+	}
+	if got, err := os.ReadFile(path); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(got, corrupt) {
+		t.Error("log was modified")
+	}
+
+	// Corrupt the data of the last record,
+	// which is treated as a partial record and removed.
+	corrupt = bytes.Clone(data)
+	corrupt[len(corrupt)-5] ^= 0xff
+	if err := os.WriteFile(path, corrupt, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d, err = OpenDurable[int](path, intCodec{}, Retain(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := d.Reader(FromEarliest())
+	d.Close()
+	got, err := r.Drain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestDurableWriteError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+
+	d, err := OpenDurable[int](path, intCodec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Write(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.WriteError(errors.New("oops")); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Write(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err = OpenDurable[int](path, intCodec{}, Retain(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := d.ReaderAt(1, ReceiveErrors())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Close()
+
+	res, ok := r.ReadResult(nil)
+	if !ok || res.Err == nil || res.Err.Error() != "oops" {
+		t.Errorf("got %v, %v; want error oops", res, ok)
+	}
+	val, offset, ok := r.ReadOffset(nil)
+	if !ok || val != 2 || offset != 2 {
+		t.Errorf("got %d at offset %d (%v), want 2 at offset 2", val, offset, ok)
+	}
+}
+
+func TestDurableCloseUnblocksWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	d, err := OpenDurable[int](path, intCodec{}, Capacity(1, Block))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := d.Reader()
+	defer r.Dispose()
+
+	if err := d.Write(1); err != nil {
+		t.Fatal(err)
+	}
+	errch := make(chan error)
+	go func() { errch <- d.Write(2) }()
+
+	// Wait for the write to block.
+	select {
+	case err := <-errch:
+		t.Fatalf("write did not block (error %v)", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	if err := d.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	closed := make(chan error)
+	go func() { closed <- d.Close() }()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close blocked")
+	}
+	if err := <-errch; err != ErrClosed {
+		t.Errorf("got %v, want %v", err, ErrClosed)
+	}
+}
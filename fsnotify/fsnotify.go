@@ -0,0 +1,155 @@
+// Package fsnotify broadcasts filesystem change events on multichans
+// using github.com/fsnotify/fsnotify,
+// so that many components watching the same files
+// can share one operating-system watcher.
+package fsnotify
+
+import (
+	"context"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/bobg/multichan"
+)
+
+// Watcher watches files and directories for changes
+// and writes the events to a multichan.
+// Create one with NewWatcher.
+type Watcher struct {
+	fw *fsnotify.Watcher
+	w  *multichan.W[fsnotify.Event]
+}
+
+// NewWatcher produces a new Watcher,
+// whose events are written to a multichan created with multichan.New(opts...).
+// Add files and directories to watch with Watcher.Add,
+// and readers with Watcher.Reader and Watcher.Debounced.
+//
+// If the underlying fsnotify.Watcher reports an error,
+// such as fsnotify.ErrEventOverflow,
+// meaning events were lost,
+// the multichan is closed with that error (see multichan.W.CloseWithError)
+// and the Watcher stops.
+func NewWatcher(opts ...multichan.Option) (*Watcher, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		fw: fw,
+		w:  multichan.New[fsnotify.Event](opts...),
+	}
+	go w.run()
+	return w, nil
+}
+
+func (w *Watcher) run() {
+	for {
+		select {
+		case ev, ok := <-w.fw.Events:
+			if !ok {
+				w.w.Close()
+				return
+			}
+			w.w.Write(ev)
+
+		case err, ok := <-w.fw.Errors:
+			if !ok {
+				w.w.Close()
+				return
+			}
+			w.w.CloseWithError(err)
+			w.fw.Close()
+			return
+		}
+	}
+}
+
+// Add starts watching the named file or directory (non-recursively).
+// See fsnotify.Watcher.Add.
+func (w *Watcher) Add(name string) error {
+	return w.fw.Add(name)
+}
+
+// Remove stops watching the named file or directory.
+// See fsnotify.Watcher.Remove.
+func (w *Watcher) Remove(name string) error {
+	return w.fw.Remove(name)
+}
+
+// Close stops watching and closes the multichan.
+func (w *Watcher) Close() error {
+	err := w.fw.Close()
+	w.w.Close()
+	return err
+}
+
+// Reader adds a new reader of events to the multichan and returns it.
+// See multichan.W.Reader.
+func (w *Watcher) Reader(opts ...multichan.ReaderOption) *multichan.R[fsnotify.Event] {
+	return w.w.Reader(opts...)
+}
+
+// Debounced adds a new reader of events to the multichan,
+// created with the given options,
+// and returns a reader of batches of them:
+// each batch is delivered once no new event has arrived for the duration d,
+// so that e.g. a burst of writes to a config file
+// causes one reload instead of many.
+//
+// The returned reader ends when the multichan is closed,
+// after the last batch.
+// Disposing of it also disposes of the underlying reader.
+func (w *Watcher) Debounced(d time.Duration, opts ...multichan.ReaderOption) *multichan.R[[]fsnotify.Event] {
+	var (
+		in   = w.w.Reader(opts...)
+		out  = multichan.New[[]fsnotify.Event]()
+		outR = out.Reader()
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		// Stop when outR is disposed of.
+		select {
+		case <-out.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ch := in.Chan(ctx)
+	go func() {
+		defer cancel()
+
+		var (
+			batch []fsnotify.Event
+			timer = time.NewTimer(d)
+		)
+		timer.Stop()
+
+		for {
+			select {
+			case ev, ok := <-ch:
+				if !ok {
+					if ctx.Err() != nil {
+						return
+					}
+					if len(batch) > 0 {
+						out.Write(batch)
+					}
+					out.CloseWithError(in.Err())
+					return
+				}
+				batch = append(batch, ev)
+				timer.Reset(d)
+
+			case <-timer.C:
+				out.Write(batch)
+				batch = nil
+			}
+		}
+	}()
+
+	return outR
+}
@@ -0,0 +1,74 @@
+package fsnotify
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+
+	w, err := NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Add(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	r := w.Reader()
+	debounced := w.Debounced(50 * time.Millisecond)
+
+	name := filepath.Join(dir, "file")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		if _, err := f.WriteString("x"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ev, ok := r.Read(ctx)
+	if !ok {
+		t.Fatal("no event")
+	}
+	if ev.Name != name || !ev.Has(fsnotify.Create) {
+		t.Errorf("got %v, want a create event for %s", ev, name)
+	}
+
+	batch, ok := debounced.Read(ctx)
+	if !ok {
+		t.Fatal("no batch")
+	}
+	if len(batch) < 2 {
+		t.Errorf("got a batch of %d events, want at least 2", len(batch))
+	}
+	for _, ev := range batch {
+		if ev.Name != name {
+			t.Errorf("got an event for %s, want %s", ev.Name, name)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, ok := debounced.Read(ctx); !ok {
+			break
+		}
+	}
+	if err := debounced.Err(); err != nil {
+		t.Errorf("got %v, want nil", err)
+	}
+}
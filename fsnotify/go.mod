@@ -0,0 +1,12 @@
+module github.com/bobg/multichan/fsnotify
+
+go 1.23
+
+require (
+	github.com/bobg/multichan v0.0.0-00010101000000-000000000000
+	github.com/fsnotify/fsnotify v1.8.0
+)
+
+require golang.org/x/sys v0.30.0 // indirect
+
+replace github.com/bobg/multichan => ../
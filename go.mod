@@ -1 +1,3 @@
 module github.com/bobg/multichan
+
+go 1.23
@@ -0,0 +1,58 @@
+package multichan
+
+// Group is a consumer group:
+// a set of readers that share a single position in the stream,
+// so that each item is delivered to exactly one member of the group
+// rather than to all of them.
+// Other readers (and other groups) on the same multichan
+// still see every item.
+//
+// A Group is a way to have N workers drain one stream.
+// Create one with W.Group
+// and a member reader for each worker with Group.Reader.
+type Group[T any] struct {
+	r        *reader[T]
+	disposed bool
+}
+
+// Group creates a new consumer group on w.
+// Its members read from the current end of the stream
+// (less any items w replays for new readers),
+// subject to the given options,
+// which apply to the group as a whole.
+//
+// The group holds its place in the stream
+// (and so keeps w from discarding unread items)
+// until the group and all its members have been disposed.
+func (w *W[T]) Group(opts ...ReaderOption) *Group[T] {
+	r := w.Reader(opts...)
+	return &Group[T]{r: r.reader}
+}
+
+// Reader adds a new member to g.
+// Each item written to the multichan
+// is read by exactly one of g's members.
+// Members should be disposed when no longer needed, like any R.
+// Calling Reader on a disposed Group panics.
+func (g *Group[T]) Reader() *R[T] {
+	g.r.w.mu.Lock()
+	defer g.r.w.mu.Unlock()
+	if g.disposed {
+		panic("multichan: Reader called on disposed Group")
+	}
+	g.r.handles++
+	return &R[T]{reader: g.r}
+}
+
+// Dispose releases g's hold on its place in the stream.
+// The group's members remain usable until they too are disposed.
+// Calling Dispose again has no effect.
+func (g *Group[T]) Dispose() {
+	g.r.w.mu.Lock()
+	defer g.r.w.mu.Unlock()
+	if g.disposed {
+		return
+	}
+	g.disposed = true
+	g.r.release()
+}
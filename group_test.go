@@ -0,0 +1,81 @@
+package multichan
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	w := New[int]()
+	g := w.Group()
+	all := w.Reader()
+
+	const members = 4
+
+	var (
+		mu  sync.Mutex
+		got []int
+		wg  sync.WaitGroup
+	)
+	for i := 0; i < members; i++ {
+		r := g.Reader()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for val := range r.All(nil) {
+				mu.Lock()
+				got = append(got, val)
+				mu.Unlock()
+			}
+		}()
+	}
+	g.Dispose()
+
+	var want []int
+	for i := 0; i < 100; i++ {
+		w.Write(i)
+		want = append(want, i)
+	}
+	w.Close()
+	wg.Wait()
+
+	sort.Ints(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("group got %v, want %v", got, want)
+	}
+
+	gotAll, _ := all.Drain(nil)
+	if !reflect.DeepEqual(gotAll, want) {
+		t.Errorf("non-member got %v, want %v", gotAll, want)
+	}
+}
+
+func TestGroupRetains(t *testing.T) {
+	w := New[int]()
+	g := w.Group()
+
+	w.Write(1)
+	w.Write(2)
+
+	r1 := g.Reader()
+	if val, ok := r1.Read(nil); !ok || val != 1 {
+		t.Errorf("got %d, %v; want 1, true", val, ok)
+	}
+	r1.Dispose()
+
+	r2 := g.Reader()
+	if val, ok := r2.Read(nil); !ok || val != 2 {
+		t.Errorf("got %d, %v; want 2, true", val, ok)
+	}
+	r2.Dispose()
+	g.Dispose()
+
+	w.mu.Lock()
+	n := len(w.readers)
+	w.mu.Unlock()
+	if n != 0 {
+		t.Errorf("got %d readers after disposing group, want 0", n)
+	}
+}
@@ -0,0 +1,51 @@
+package multichan
+
+// Hooks is an interface for instrumenting a multichan
+// with tracing, logging, metrics, and the like.
+// Install an implementation with the Instrument option.
+// Embed NopHooks in an implementation
+// to supply no-op versions of the methods it does not need.
+//
+// The methods are called while the multichan is locked,
+// so they must not block
+// and must not call methods on the multichan or its readers.
+type Hooks[T any] interface {
+	// OnWrite is called when an item is added to the multichan,
+	// with its stream offset (see R.ReadOffset).
+	OnWrite(val T, offset int64)
+
+	// OnRead is called when a reader reads an item.
+	// The reader is described as it is after reading.
+	OnRead(val T, offset int64, reader ReaderInfo)
+
+	// OnReaderAdded is called when a reader is added to the multichan.
+	OnReaderAdded(reader ReaderInfo)
+
+	// OnReaderDisposed is called when a reader is disposed of or evicted.
+	OnReaderDisposed(reader ReaderInfo)
+
+	// OnClose is called when the multichan is closed,
+	// with the error passed to CloseWithError, if any.
+	OnClose(err error)
+}
+
+// NopHooks is an implementation of Hooks whose methods do nothing.
+type NopHooks[T any] struct{}
+
+var _ Hooks[int] = NopHooks[int]{}
+
+func (NopHooks[T]) OnWrite(T, int64)            {}
+func (NopHooks[T]) OnRead(T, int64, ReaderInfo) {}
+func (NopHooks[T]) OnReaderAdded(ReaderInfo)    {}
+func (NopHooks[T]) OnReaderDisposed(ReaderInfo) {}
+func (NopHooks[T]) OnClose(error)               {}
+
+// Instrument installs the given hooks in a multichan.
+//
+// The type parameter T must match that of the multichan,
+// or New will panic (see NewChecked).
+func Instrument[T any](h Hooks[T]) Option {
+	return func(c *config) {
+		c.hooks = h
+	}
+}
@@ -0,0 +1,58 @@
+package multichan
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type recordingHooks struct {
+	NopHooks[int]
+	events []string
+}
+
+func (h *recordingHooks) OnWrite(val int, offset int64) {
+	h.events = append(h.events, fmt.Sprintf("write %d@%d", val, offset))
+}
+
+func (h *recordingHooks) OnRead(val int, offset int64, reader ReaderInfo) {
+	h.events = append(h.events, fmt.Sprintf("read %d@%d by %s", val, offset, reader.Name))
+}
+
+func (h *recordingHooks) OnReaderAdded(reader ReaderInfo) {
+	h.events = append(h.events, "add "+reader.Name)
+}
+
+func (h *recordingHooks) OnReaderDisposed(reader ReaderInfo) {
+	h.events = append(h.events, "dispose "+reader.Name)
+}
+
+func (h *recordingHooks) OnClose(err error) {
+	h.events = append(h.events, fmt.Sprintf("close %v", err))
+}
+
+func TestHooks(t *testing.T) {
+	h := new(recordingHooks)
+	w := New[int](Instrument[int](h))
+
+	r := w.Reader(Name("r"))
+	w.WriteBatch([]int{1, 2})
+	r.Read(nil)
+	r.ReadN(nil, 0)
+	w.CloseWithError(errors.New("done"))
+	r.Dispose()
+
+	want := []string{
+		"add r",
+		"write 1@0",
+		"write 2@1",
+		"read 1@0 by r",
+		"read 2@1 by r",
+		"close done",
+		"dispose r",
+	}
+	if !reflect.DeepEqual(h.events, want) {
+		t.Errorf("got %v, want %v", h.events, want)
+	}
+}
@@ -0,0 +1,204 @@
+package multichan
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"sync"
+)
+
+// IOWriter is an io.WriteCloser that writes to a multichan of byte slices,
+// so that code writing to an io.Writer,
+// such as a logger or an encoder,
+// can broadcast to the multichan's readers.
+// Create one with NewIOWriter.
+type IOWriter struct {
+	mu    sync.Mutex
+	w     *W[[]byte]
+	split bufio.SplitFunc
+	buf   []byte // data not yet split into items
+}
+
+var _ io.WriteCloser = (*IOWriter)(nil)
+
+// NewIOWriter produces a new IOWriter writing to w.
+//
+// If split is nil,
+// the data of each call to Write becomes one item.
+// Otherwise the data written is buffered
+// and divided into items by split,
+// as in a bufio.Scanner:
+// e.g., with bufio.ScanLines,
+// each line written becomes an item,
+// without its line ending.
+func NewIOWriter(w *W[[]byte], split bufio.SplitFunc) *IOWriter {
+	return &IOWriter{w: w, split: split}
+}
+
+// Write implements io.Writer.
+// Items are written to the multichan with W.Write,
+// which may block (see Capacity),
+// and whose error, if any, is returned.
+func (iw *IOWriter) Write(p []byte) (int, error) {
+	iw.mu.Lock()
+	defer iw.mu.Unlock()
+
+	if iw.split == nil {
+		if err := iw.w.Write(bytes.Clone(p)); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	iw.buf = append(iw.buf, p...)
+	if err := iw.scan(false); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close implements io.Closer.
+// It writes any remaining buffered data as a final item
+// (if the split function produces one at the end of the input)
+// and closes the multichan.
+// If splitting or writing the remaining data fails,
+// the multichan is closed with that error (see W.CloseWithError),
+// which Close returns.
+func (iw *IOWriter) Close() error {
+	iw.mu.Lock()
+	defer iw.mu.Unlock()
+
+	var err error
+	if iw.split != nil {
+		err = iw.scan(true)
+		iw.buf = nil
+	}
+	iw.w.CloseWithError(err)
+	return err
+}
+
+// scan writes the items that iw.split finds in iw.buf
+// and removes their data from it.
+// Callers must hold iw.mu.
+func (iw *IOWriter) scan(atEOF bool) error {
+	data := iw.buf
+	for len(data) > 0 {
+		advance, token, err := iw.split(data, atEOF)
+		if err != nil {
+			return err
+		}
+		if advance < 0 {
+			return bufio.ErrNegativeAdvance
+		}
+		if advance > len(data) {
+			return bufio.ErrAdvanceTooFar
+		}
+		if token != nil {
+			if err := iw.w.Write(bytes.Clone(token)); err != nil {
+				return err
+			}
+		}
+		if advance == 0 {
+			// More data is needed.
+			break
+		}
+		data = data[advance:]
+	}
+	iw.buf = append(iw.buf[:0], data...)
+	return nil
+}
+
+// IOReader is an io.ReadCloser that reads from a multichan of byte slices,
+// concatenating its items,
+// so that code reading from an io.Reader,
+// such as a json.Decoder or a bufio.Scanner,
+// can consume the stream.
+// Create one with NewIOReader.
+type IOReader struct {
+	mu  sync.Mutex
+	ctx context.Context
+	r   *R[[]byte]
+	buf []byte // the unread part of the current item
+}
+
+var _ io.ReadCloser = (*IOReader)(nil)
+
+// NewIOReader produces a new IOReader reading from r.
+// Calls to Read block waiting for items
+// until the context is canceled,
+// after which they return the context's error.
+// The context argument may be nil.
+func NewIOReader(ctx context.Context, r *R[[]byte]) *IOReader {
+	return &IOReader{ctx: ctx, r: r}
+}
+
+// Read implements io.Reader.
+// It blocks until an item is ready to read,
+// then returns as much of it as fits in p;
+// the rest is returned by later calls.
+// Empty items are skipped.
+//
+// Once the multichan is closed and its last item has been consumed,
+// Read returns io.EOF,
+// or the multichan's error if it was closed with one (see W.CloseWithError),
+// or the reader's error if it was evicted or disposed of (see R.Err).
+func (ir *IOReader) Read(p []byte) (int, error) {
+	ir.mu.Lock()
+	defer ir.mu.Unlock()
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for len(ir.buf) == 0 {
+		val, ok := ir.r.Read(ir.ctx)
+		if !ok {
+			if ir.ctx != nil && ir.ctx.Err() != nil {
+				return 0, ir.ctx.Err()
+			}
+			if err := ir.r.Err(); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+		ir.buf = val
+	}
+	n := copy(p, ir.buf)
+	ir.buf = ir.buf[n:]
+	return n, nil
+}
+
+// Close implements io.Closer.
+// It disposes of the reader (see R.Dispose).
+func (ir *IOReader) Close() error {
+	ir.r.Dispose()
+	return nil
+}
+
+// ScanReader reads in until the end of its data,
+// divides the data into items with split,
+// as in a bufio.Scanner,
+// and writes the items to w,
+// so that e.g. the lines of a file or of a command's output
+// can be broadcast to many readers.
+// If split is nil, bufio.ScanLines is used.
+//
+// At the end of the data,
+// ScanReader closes w and returns nil.
+// If reading, splitting, or writing fails first,
+// ScanReader closes w with that error (see W.CloseWithError)
+// and returns it.
+// To stop ScanReader early,
+// close in (if it is an io.Closer)
+// or w.
+func ScanReader(w *W[[]byte], in io.Reader, split bufio.SplitFunc) error {
+	if split == nil {
+		split = bufio.ScanLines
+	}
+	iw := NewIOWriter(w, split)
+	if _, err := io.Copy(iw, in); err != nil {
+		w.CloseWithError(err)
+		return err
+	}
+	return iw.Close()
+}
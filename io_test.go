@@ -0,0 +1,143 @@
+package multichan
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func readStrings(r *R[[]byte]) []string {
+	var got []string
+	for {
+		val, ok := r.Read(nil)
+		if !ok {
+			return got
+		}
+		got = append(got, string(val))
+	}
+}
+
+func TestIOWriter(t *testing.T) {
+	w := New[[]byte]()
+	r := w.Reader()
+
+	iw := NewIOWriter(w, nil)
+	buf := []byte("abc")
+	iw.Write(buf)
+	buf[0] = 'x' // must not affect the item
+	fmt.Fprint(iw, "def")
+	if err := iw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := readStrings(r), []string{"abc", "def"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, err := iw.Write([]byte("ghi")); err != ErrClosed {
+		t.Errorf("got %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestIOWriterSplit(t *testing.T) {
+	w := New[[]byte]()
+	r := w.Reader()
+
+	iw := NewIOWriter(w, bufio.ScanLines)
+	fmt.Fprint(iw, "one\ntw")
+	fmt.Fprint(iw, "o\n\nthr")
+	if n := r.Pending(); n != 3 {
+		t.Errorf("got %d pending items, want 3", n)
+	}
+	fmt.Fprint(iw, "ee")
+	if err := iw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := readStrings(r), []string{"one", "two", "", "three"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestIOReader(t *testing.T) {
+	w := New[[]byte]()
+	ir := NewIOReader(nil, w.Reader())
+	defer ir.Close()
+
+	w.WriteBatch([][]byte{[]byte(`{"a": 1}`), nil, []byte(` {"a"`), []byte(`: 2}`)})
+	w.Close()
+
+	var got []int
+	dec := json.NewDecoder(ir)
+	for {
+		var obj struct{ A int }
+		if err := dec.Decode(&obj); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, obj.A)
+	}
+	if want := []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestIOReaderShort(t *testing.T) {
+	w := New[[]byte]()
+	ir := NewIOReader(nil, w.Reader())
+
+	w.Write([]byte("hello"))
+	errTest := errors.New("test")
+	w.CloseWithError(errTest)
+
+	buf := make([]byte, 3)
+	n, err := ir.Read(buf)
+	if err != nil || string(buf[:n]) != "hel" {
+		t.Errorf("got %q, %v; want \"hel\", nil", buf[:n], err)
+	}
+	n, err = ir.Read(buf)
+	if err != nil || string(buf[:n]) != "lo" {
+		t.Errorf("got %q, %v; want \"lo\", nil", buf[:n], err)
+	}
+	if _, err := ir.Read(buf); err != errTest {
+		t.Errorf("got %v, want %v", err, errTest)
+	}
+}
+
+func TestScanReader(t *testing.T) {
+	w := New[[]byte]()
+	r := w.Reader()
+
+	if err := ScanReader(w, strings.NewReader("one two\nthree"), bufio.ScanWords); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readStrings(r), []string{"one", "two", "three"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if err := r.Err(); err != nil {
+		t.Errorf("got %v, want nil", err)
+	}
+}
+
+func TestScanReaderError(t *testing.T) {
+	w := New[[]byte]()
+	r := w.Reader()
+
+	errTest := errors.New("test")
+	in := io.MultiReader(strings.NewReader("one\ntwo\nth"), iotest.ErrReader(errTest))
+	if err := ScanReader(w, in, nil); err != errTest {
+		t.Errorf("got %v, want %v", err, errTest)
+	}
+	if got, want := readStrings(r), []string{"one", "two"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if err := r.Err(); err != errTest {
+		t.Errorf("got %v, want %v", err, errTest)
+	}
+}
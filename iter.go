@@ -0,0 +1,56 @@
+package multichan
+
+import (
+	"context"
+	"iter"
+)
+
+// All returns an iterator over the items read from r,
+// for use in a range-over-func loop:
+//
+//	for val := range r.All(ctx) {
+//	  ...
+//	}
+//
+// Iteration ends when the multichan is closed and the last item has been consumed,
+// or when the context is canceled
+// (after which r.Err or ctx.Err can tell why).
+// The iterator disposes of r when iteration ends,
+// including when the loop exits early.
+// The context argument may be nil.
+func (r *R[T]) All(ctx context.Context) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		defer r.Dispose()
+
+		for {
+			val, ok := r.Read(ctx)
+			if !ok || !yield(val) {
+				return
+			}
+		}
+	}
+}
+
+// Indexed is like All
+// but its iterator yields each item's stream offset
+// (see R.ReadOffset)
+// along with the item,
+// so that a consumer can record a checkpoint
+// (for use with W.ReaderAt)
+// as it goes:
+//
+//	for offset, val := range r.Indexed(ctx) {
+//	  ...
+//	}
+func (r *R[T]) Indexed(ctx context.Context) iter.Seq2[int64, T] {
+	return func(yield func(int64, T) bool) {
+		defer r.Dispose()
+
+		for {
+			val, offset, ok := r.ReadOffset(ctx)
+			if !ok || !yield(offset, val) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package multichan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAll(t *testing.T) {
+	w := New[int]()
+	r := w.Reader()
+
+	w.WriteBatch([]int{1, 2, 3})
+	w.Close()
+
+	var got []int
+	for val := range r.All(nil) {
+		got = append(got, val)
+	}
+	if !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("got %v, want [1, 2, 3]", got)
+	}
+
+	w = New[int]()
+	r = w.Reader()
+	w.WriteBatch([]int{1, 2, 3})
+
+	got = nil
+	for val := range r.All(nil) {
+		got = append(got, val)
+		if val == 2 {
+			break
+		}
+	}
+	if !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("got %v, want [1, 2]", got)
+	}
+
+	w.mu.Lock()
+	n := len(w.readers)
+	w.mu.Unlock()
+	if n != 0 {
+		t.Errorf("got %d readers after early exit, want 0", n)
+	}
+}
+
+func TestIndexed(t *testing.T) {
+	w := New[string]()
+	w.Write("x")
+
+	r := w.Reader()
+	w.WriteBatch([]string{"a", "b", "c"})
+	w.Close()
+
+	var (
+		offsets []int64
+		vals    []string
+	)
+	for offset, val := range r.Indexed(nil) {
+		offsets = append(offsets, offset)
+		vals = append(vals, val)
+	}
+	if !reflect.DeepEqual(offsets, []int64{1, 2, 3}) {
+		t.Errorf("got offsets %v, want [1 2 3]", offsets)
+	}
+	if !reflect.DeepEqual(vals, []string{"a", "b", "c"}) {
+		t.Errorf("got values %v, want [a b c]", vals)
+	}
+}
@@ -0,0 +1,15 @@
+module github.com/bobg/multichan/kafka
+
+go 1.23
+
+require (
+	github.com/bobg/multichan v0.0.0-00010101000000-000000000000
+	github.com/segmentio/kafka-go v0.4.49
+)
+
+require (
+	github.com/klauspost/compress v1.18.0 // indirect
+	github.com/pierrec/lz4/v4 v4.1.15 // indirect
+)
+
+replace github.com/bobg/multichan => ../
@@ -0,0 +1,184 @@
+// Package kafka connects multichans to Kafka topics
+// using github.com/segmentio/kafka-go.
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/bobg/multichan"
+)
+
+// Writer is the part of *kafka.Writer used by Sink.
+type Writer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// Reader is the part of *kafka.Reader used by Source.
+// The reader must be part of a consumer group
+// (see kafka.ReaderConfig.GroupID),
+// since Source commits the offsets of the messages it consumes.
+type Reader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+var (
+	_ Writer = (*kafka.Writer)(nil)
+	_ Reader = (*kafka.Reader)(nil)
+)
+
+// maxBatch is the largest number of items Sink sends to Kafka at once.
+const maxBatch = 100
+
+// Sink reads items from r,
+// converts each to a Kafka message with encode,
+// and writes the messages to kw,
+// in batches of those that are ready.
+//
+// If r was created with multichan.W.ResumeReader,
+// its position is committed (see multichan.R.Commit)
+// after each batch is written,
+// so that a sink resumed with the same name
+// continues with the first item not written to Kafka.
+// (If the process stops after writing a batch but before committing,
+// the batch is written again.)
+//
+// Sink runs until r has consumed the last item of its closed multichan,
+// returning r's error if any (see multichan.R.Err),
+// or until the context is canceled,
+// or until encoding, writing, or committing fails,
+// in which case it returns the error.
+func Sink[T any](ctx context.Context, r *multichan.R[T], kw Writer, encode func(T) (kafka.Message, error)) error {
+	for {
+		vals := r.ReadN(ctx, maxBatch)
+		if len(vals) == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return r.Err()
+		}
+		msgs := make([]kafka.Message, 0, len(vals))
+		for _, val := range vals {
+			msg, err := encode(val)
+			if err != nil {
+				return fmt.Errorf("encoding item: %w", err)
+			}
+			msgs = append(msgs, msg)
+		}
+		if err := kw.WriteMessages(ctx, msgs...); err != nil {
+			return fmt.Errorf("writing messages: %w", err)
+		}
+		if err := r.Commit(); err != nil && !errors.Is(err, multichan.ErrNoOffsetStore) {
+			return fmt.Errorf("committing reader position: %w", err)
+		}
+	}
+}
+
+// Source fetches messages from kr,
+// converts each to an item with decode,
+// and writes the items to w.
+//
+// The offset of a message is committed to Kafka
+// only once every reader of w has consumed its item
+// (see multichan.W.Flush),
+// so that a source resumed in the same consumer group
+// continues with the first message not consumed by every reader.
+// Commits are batched,
+// so the items of many messages may be consumed before their offsets are committed.
+// (If the process stops in between,
+// those messages are fetched again.)
+//
+// Source runs until the context is canceled,
+// or until w is closed,
+// or until fetching, decoding, or committing fails,
+// in which case it returns the error.
+// It does not close w.
+func Source[T any](ctx context.Context, kr Reader, w *multichan.W[T], decode func(kafka.Message) (T, error)) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	written := make(chan kafka.Message, maxBatch)
+	committed := make(chan struct{})
+	go func() {
+		defer close(committed)
+		if err := commit(ctx, kr, w, written); err != nil {
+			cancel(err)
+		}
+	}()
+
+	err := fetch(ctx, kr, w, decode, written)
+	close(written)
+	<-committed
+
+	if cause := context.Cause(ctx); cause != nil && !errors.Is(cause, context.Canceled) {
+		// Committing failed.
+		return cause
+	}
+	return err
+}
+
+// fetch implements the fetching part of Source,
+// sending each message to written once its item is written to w.
+func fetch[T any](ctx context.Context, kr Reader, w *multichan.W[T], decode func(kafka.Message) (T, error), written chan<- kafka.Message) error {
+	for {
+		msg, err := kr.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("fetching message: %w", err)
+		}
+		val, err := decode(msg)
+		if err != nil {
+			return fmt.Errorf("decoding message at offset %d of partition %d: %w", msg.Offset, msg.Partition, err)
+		}
+		if err := w.WriteContext(ctx, val); err != nil {
+			return err
+		}
+		select {
+		case written <- msg:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// commit implements the committing part of Source.
+// It commits the messages received on written,
+// in batches,
+// each after the readers of w consume its items.
+func commit[T any](ctx context.Context, kr Reader, w *multichan.W[T], written <-chan kafka.Message) error {
+	for {
+		msg, ok := <-written
+		if !ok {
+			return nil
+		}
+		msgs := []kafka.Message{msg}
+	BATCH:
+		for {
+			select {
+			case msg, ok := <-written:
+				if !ok {
+					break BATCH
+				}
+				msgs = append(msgs, msg)
+			default:
+				break BATCH
+			}
+		}
+
+		if err := w.Flush(ctx); err != nil {
+			return nil // canceled
+		}
+		if err := kr.CommitMessages(ctx, msgs...); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("committing messages: %w", err)
+		}
+	}
+}
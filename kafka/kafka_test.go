@@ -0,0 +1,134 @@
+package kafka
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/bobg/multichan"
+)
+
+type fakeWriter struct {
+	msgs []kafka.Message
+}
+
+func (f *fakeWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	f.msgs = append(f.msgs, msgs...)
+	return nil
+}
+
+type fakeReader struct {
+	mu        sync.Mutex
+	msgs      []kafka.Message
+	committed []int64
+}
+
+func (f *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	f.mu.Lock()
+	if len(f.msgs) > 0 {
+		msg := f.msgs[0]
+		f.msgs = f.msgs[1:]
+		f.mu.Unlock()
+		return msg, nil
+	}
+	f.mu.Unlock()
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (f *fakeReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, msg := range msgs {
+		f.committed = append(f.committed, msg.Offset)
+	}
+	return nil
+}
+
+func (f *fakeReader) numCommitted() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.committed)
+}
+
+func TestSink(t *testing.T) {
+	store := multichan.DirOffsetStore(t.TempDir())
+	w := multichan.New[int](multichan.Retain(10))
+	r, err := w.ResumeReader("sink", store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteBatch([]int{1, 2, 3})
+	w.Close()
+
+	var kw fakeWriter
+	encode := func(n int) (kafka.Message, error) {
+		return kafka.Message{Value: []byte(strconv.Itoa(n))}, nil
+	}
+	if err := Sink(context.Background(), r, &kw, encode); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, msg := range kw.msgs {
+		got = append(got, string(msg.Value))
+	}
+	if want := []string{"1", "2", "3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if offset, ok, err := store.LoadOffset("sink"); err != nil || !ok || offset != 3 {
+		t.Errorf("got committed offset %d, %v, %v; want 3, true, nil", offset, ok, err)
+	}
+}
+
+func TestSource(t *testing.T) {
+	kr := &fakeReader{}
+	for i := range 3 {
+		kr.msgs = append(kr.msgs, kafka.Message{Offset: int64(i), Value: []byte(strconv.Itoa(i + 1))})
+	}
+
+	w := multichan.New[int]()
+	r := w.Reader()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Source(ctx, kr, w, func(msg kafka.Message) (int, error) {
+			return strconv.Atoi(string(msg.Value))
+		})
+	}()
+
+	var got []int
+	for len(got) < 2 {
+		val, _ := r.Read(ctx)
+		got = append(got, val)
+	}
+
+	// Nothing is committed until the reader consumes the item.
+	time.Sleep(10 * time.Millisecond)
+	if n := kr.numCommitted(); n > 2 {
+		t.Errorf("got %d committed messages, want at most 2", n)
+	}
+
+	val, _ := r.Read(ctx)
+	got = append(got, val)
+	for kr.numCommitted() < 3 {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if want := []int64{0, 1, 2}; !reflect.DeepEqual(kr.committed, want) {
+		t.Errorf("got committed offsets %v, want %v", kr.committed, want)
+	}
+}
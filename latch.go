@@ -0,0 +1,77 @@
+package multichan
+
+import (
+	"context"
+	"sync"
+)
+
+// Latch is a value that is set once
+// and then available to any number of waiters,
+// current and future:
+// a broadcast promise.
+// It is a multichan that is written once and then closed,
+// retaining its one item for new readers (see Sticky).
+//
+// Create one with NewLatch.
+type Latch[T any] struct {
+	mu  sync.Mutex
+	w   *W[T]
+	val T
+	set bool
+}
+
+// NewLatch produces a new, unset Latch.
+func NewLatch[T any]() *Latch[T] {
+	return &Latch[T]{w: New[T](Sticky())}
+}
+
+// SetOnce sets the latch's value,
+// releasing all waiters,
+// and reports true.
+// If the value is already set,
+// SetOnce does nothing and reports false.
+func (l *Latch[T]) SetOnce(val T) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.set {
+		return false
+	}
+	l.val, l.set = val, true
+	l.w.Write(val)
+	l.w.Close()
+	return true
+}
+
+// Get returns the latch's value and true if it is set,
+// or the zero value of T and false if not.
+// It does not block.
+func (l *Latch[T]) Get() (T, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.val, l.set
+}
+
+// Wait blocks until the latch's value is set,
+// then returns it.
+// If the context is canceled first,
+// Wait returns the context's error.
+// The context argument may be nil.
+func (l *Latch[T]) Wait(ctx context.Context) (T, error) {
+	r := l.w.Reader()
+	defer r.Dispose()
+
+	val, ok := r.Read(ctx)
+	if !ok && ctx != nil {
+		return val, ctx.Err()
+	}
+	return val, nil
+}
+
+// Reader returns a reader of the latch's underlying multichan,
+// which produces the value once it is set
+// and then ends
+// (e.g. for use with R.Chan in a select statement).
+func (l *Latch[T]) Reader(opts ...ReaderOption) *R[T] {
+	return l.w.Reader(opts...)
+}
@@ -0,0 +1,62 @@
+package multichan
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLatch(t *testing.T) {
+	l := NewLatch[string]()
+
+	if _, ok := l.Get(); ok {
+		t.Error("got a value from an unset latch")
+	}
+
+	var (
+		wg  sync.WaitGroup
+		got = make([]string, 3)
+	)
+	for i := range got {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got[i], _ = l.Wait(nil)
+		}()
+	}
+
+	if !l.SetOnce("a") {
+		t.Error("first SetOnce returned false")
+	}
+	if l.SetOnce("b") {
+		t.Error("second SetOnce returned true")
+	}
+	wg.Wait()
+	for _, val := range got {
+		if val != "a" {
+			t.Errorf("got %q, want \"a\"", val)
+		}
+	}
+
+	// Later waiters get the value at once.
+	if val, err := l.Wait(nil); err != nil || val != "a" {
+		t.Errorf("got %q, %v; want \"a\", nil", val, err)
+	}
+	if val, ok := l.Get(); !ok || val != "a" {
+		t.Errorf("got %q, %v; want \"a\", true", val, ok)
+	}
+	if val, ok := <-l.Reader().Chan(nil); !ok || val != "a" {
+		t.Errorf("got %q, %v; want \"a\", true", val, ok)
+	}
+}
+
+func TestLatchCancel(t *testing.T) {
+	l := NewLatch[int]()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
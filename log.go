@@ -0,0 +1,54 @@
+package multichan
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LogLevels gives the levels at which a multichan logs notable events
+// (see Logger).
+type LogLevels struct {
+	Readers slog.Level // the addition and disposal of readers
+	Close   slog.Level // the closing of the multichan
+	Drop    slog.Level // items dropped before every reader consumed them (see DeadLetter)
+	Lag     slog.Level // readers falling behind and recovering (see OnLag)
+	Spill   slog.Level // failures to spill items to disk (see Spill)
+}
+
+// DefaultLogLevels are reasonable levels for use with Logger.
+var DefaultLogLevels = LogLevels{
+	Readers: slog.LevelDebug,
+	Close:   slog.LevelInfo,
+	Drop:    slog.LevelWarn,
+	Lag:     slog.LevelWarn,
+	Spill:   slog.LevelError,
+}
+
+// Logger causes a multichan to log notable events
+// to the given logger
+// at the given levels.
+// Readers falling behind and recovering are logged
+// only if the multichan has lag thresholds (see OnLag).
+func Logger(logger *slog.Logger, levels LogLevels) Option {
+	return func(c *config) {
+		c.logger = logger
+		c.logLevels = levels
+	}
+}
+
+// log logs msg with the given attributes at the given level, if w has a logger.
+// Callers must hold w.mu.
+func (w *W[T]) log(level slog.Level, msg string, attrs ...slog.Attr) {
+	if w.logger != nil {
+		w.logger.LogAttrs(context.Background(), level, msg, attrs...)
+	}
+}
+
+// readerAttrs produces logging attributes describing a reader.
+func readerAttrs(info ReaderInfo) []slog.Attr {
+	return []slog.Attr{
+		slog.String("reader", info.Name),
+		slog.Int64("pos", info.Pos),
+		slog.Int("lag", info.Lag),
+	}
+}
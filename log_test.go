@@ -0,0 +1,48 @@
+package multichan
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	buf := new(strings.Builder)
+	logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	w := New[int](Capacity(2, DropOldest), OnLag(2, 0, nil), Logger(logger, DefaultLogLevels))
+	r := w.Reader(Name("a"))
+	w.WriteBatch([]int{1, 2, 3})
+	for range 2 {
+		r.NBRead()
+	}
+	r.Dispose()
+	w.CloseWithError(errors.New("done"))
+
+	want := []string{
+		`level=DEBUG msg="multichan reader added" reader=a pos=0 lag=0`,
+		`level=WARN msg="multichan reader behind" reader=a pos=0 lag=2`,
+		`level=WARN msg="multichan item dropped" reason=overflow`,
+		`level=WARN msg="multichan reader recovered" reader=a pos=3 lag=0`,
+		`level=DEBUG msg="multichan reader disposed" reader=a pos=3 lag=0`,
+		`level=INFO msg="multichan closed" error=done`,
+	}
+	got := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(got) != len(want) {
+		t.Fatalf("got %d log lines, want %d:\n%s", len(got), len(want), buf)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d: got %s, want %s", i, got[i], want[i])
+		}
+	}
+}
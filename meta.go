@@ -0,0 +1,57 @@
+package multichan
+
+import (
+	"context"
+	"maps"
+	"time"
+)
+
+// Meta is the metadata of an item,
+// recorded when the item is written.
+// See R.ReadMeta.
+type Meta struct {
+	// Offset is the stream offset of the item (see R.ReadOffset),
+	// which serves as its sequence number:
+	// the first item ever written to a multichan is at offset 0,
+	// the next at offset 1,
+	// and so on.
+	Offset int64
+
+	// Time is when the item was written,
+	// according to the multichan's clock (see UseClock).
+	// For an item replayed from a log (see OpenDurable)
+	// or loaded from a snapshot (see Load),
+	// it is when the item was replayed or loaded.
+	Time time.Time
+
+	// Headers are the headers the item was written with (see W.WriteWithMeta),
+	// or nil.
+	// They are shared by the item's readers
+	// and must not be modified.
+	Headers map[string]string
+}
+
+// WriteWithMeta is like Write,
+// but attaches the given headers to the item,
+// such as a tracing ID or a schema version,
+// for its readers to retrieve with R.ReadMeta.
+// The headers are copied.
+//
+// Headers are not recorded in logs (see OpenDurable)
+// or snapshots (see W.Save).
+func (w *W[T]) WriteWithMeta(val T, headers map[string]string) error {
+	return w.write(nil, item[T]{val: val, headers: maps.Clone(headers)}, w.ttl)
+}
+
+// ReadMeta is like Read
+// but also returns the metadata of the item read,
+// e.g. for computing the latency between writing and reading it.
+func (r *R[T]) ReadMeta(ctx context.Context) (T, Meta, bool) {
+	it, ok := r.read(ctx)
+	return it.val, it.meta(), ok
+}
+
+// meta returns the metadata of it.
+func (it *item[T]) meta() Meta {
+	return Meta{Offset: it.offset, Time: it.written, Headers: it.headers}
+}
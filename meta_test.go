@@ -0,0 +1,55 @@
+package multichan
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadMeta(t *testing.T) {
+	w := New[string]()
+	r := w.Reader()
+
+	before := time.Now()
+	w.Write("a")
+	w.Write("b")
+	after := time.Now()
+	w.Close()
+
+	for i, want := range []string{"a", "b"} {
+		val, meta, ok := r.ReadMeta(nil)
+		if !ok {
+			t.Fatalf("unexpected failure from ReadMeta")
+		}
+		if val != want {
+			t.Errorf("got %q, want %q", val, want)
+		}
+		if meta.Offset != int64(i) {
+			t.Errorf("got offset %d, want %d", meta.Offset, i)
+		}
+		if meta.Time.Before(before) || meta.Time.After(after) {
+			t.Errorf("got time %v, want between %v and %v", meta.Time, before, after)
+		}
+	}
+
+	if _, meta, ok := r.ReadMeta(nil); ok || !reflect.DeepEqual(meta, Meta{}) {
+		t.Errorf("got %v, %v; want zero Meta, false", meta, ok)
+	}
+}
+
+func TestWriteWithMeta(t *testing.T) {
+	w := New[int]()
+	r := w.Reader()
+
+	headers := map[string]string{"trace-id": "abc"}
+	w.WriteWithMeta(1, headers)
+	headers["trace-id"] = "xyz" // must not affect the item
+	w.Write(2)
+
+	if val, meta, ok := r.ReadMeta(nil); !ok || val != 1 || !reflect.DeepEqual(meta.Headers, map[string]string{"trace-id": "abc"}) {
+		t.Errorf("got %d, %v, %v; want 1, headers [trace-id:abc], true", val, meta.Headers, ok)
+	}
+	if val, meta, ok := r.ReadMeta(nil); !ok || val != 2 || meta.Headers != nil {
+		t.Errorf("got %d, %v, %v; want 2, no headers, true", val, meta.Headers, ok)
+	}
+}
@@ -1,173 +1,2087 @@
 package multichan
 
 import (
+	"container/heap"
 	"context"
+	"errors"
+	"expvar"
 	"fmt"
-	"reflect"
+	"log/slog"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// W is the writing end of a one-to-many data channel.
-type W struct {
-	mu   sync.Mutex
-	cond sync.Cond
+// ErrClosed is the error returned when writing to a closed multichan.
+var ErrClosed = errors.New("multichan closed")
 
-	zero     interface{}  // the zero value of this channel
-	zerotype reflect.Type // the type of the zero value
+// ErrOffsetRange is the error returned when positioning a reader at a stream offset
+// that is not in the range of items retained by a multichan.
+var ErrOffsetRange = errors.New("offset out of range")
 
-	closed bool
+// ErrOptionType is the error wrapped by the errors from NewChecked and W.ReaderAt
+// when an option that depends on the item type (such as RetainBytes or Where)
+// is for items of a type other than the multichan's.
+var ErrOptionType = errors.New("option does not match multichan item type")
 
-	head *item
+// ErrExpvarName is the error wrapped by the error from NewChecked
+// when the name given to the Expvar option is already in use.
+var ErrExpvarName = errors.New("expvar name in use")
 
-	pendingReaders []*R // readers that don't have their next field set yet
+// ErrEvicted is the error reported by R.Err
+// when the reader was disposed of by the EvictSlowest overflow policy.
+var ErrEvicted = errors.New("reader evicted")
+
+// ErrDisposed is the error returned when using a reader after calling its Dispose method.
+var ErrDisposed = errors.New("reader disposed")
+
+// ErrNoOffsetStore is the error returned by R.Commit
+// for a reader not created with W.ResumeReader.
+var ErrNoOffsetStore = errors.New("reader has no offset store")
+
+// ErrBadToken is the error returned by W.ReaderAtToken
+// when its token was not produced by R.Token.
+var ErrBadToken = errors.New("invalid token")
+
+// W is the writing end of a one-to-many data channel of items of type T.
+type W[T any] struct {
+	mu sync.Mutex
+
+	// Closed (and cleared) to wake writers and callers of Flush
+	// waiting for a change in w's state.
+	// Created only when some goroutine waits (see await).
+	// Readers wait separately (see reader.await).
+	changed chan struct{}
+	waiting map[*reader[T]]struct{} // readers with a wake channel
+
+	closed   bool
+	err      error         // the error passed to CloseWithError
+	closedCh chan struct{} // closed when w is; created on demand (see closedChan)
+
+	// The retained items, in stream order:
+	// those not yet consumed by every reader,
+	// plus any kept by the retention policy (see Retain, RetainFor, RetainBytes, and Compact).
+	// Some of these may be dead (see item).
+	items queue[T]
+	dead  int // the number of dead items in items
+
+	offset int64 // the lowest position a reader may have
+	next   int64 // the stream offset of the next item to be written
+
+	published atomic.Int64 // a copy of next that may be read without holding mu (see NBRead)
+
+	// The active readers, slowest first.
+	// Disposing of a reader removes it from this heap,
+	// so churning readers do not accumulate here.
+	readers readerHeap[T]
+
+	flushers int // the number of callers waiting in Flush
+
+	// See Done.
+	hadReaders bool
+	idle       chan struct{}
+	idleClosed bool
+
+	capacity    int
+	overflow    Overflow
+	retain      int
+	retainFor   time.Duration
+	retainBytes int
+	sizer       func(T) int
+	bytes       int64 // total size of all items written (see RetainBytes)
+	replay      int   // how far back from the end new readers start
+
+	key     func(T) any   // see Compact
+	latest  map[any]int64 // the offset of the newest item with each key
+	scanned int64         // items before this offset have been checked for compaction
+
+	eq       func(a, b T) bool // see DedupConsecutive
+	prev     T                 // the most recently written item, if eq is set
+	havePrev bool
+
+	ttl      time.Duration // see TTL
+	expiries expiryHeap
+	timer    Timer // fires at the earliest expiry
+
+	clock Clock // see UseClock
+
+	prioritized bool // see Prioritized
+	autoClose   bool // see AutoClose
+	hold        bool // see HoldUntilReader
+	hasErrors   bool // whether any errors have been written (see WriteError)
+
+	// The numbers of readers with filters (see Where),
+	// receiving errors (see ReceiveErrors),
+	// and with lag limits (see MaxLag),
+	// so that writes need not check every reader for these.
+	numFiltered, numErrors, numMaxLag int
+
+	deadLetter func(T, DropReason) // see DeadLetter
+
+	trimmed, dropped int64 // see Stats
+
+	lagHigh, lagLow int // see OnLag
+	onLag           func(ReaderInfo, bool)
+
+	onDrop func(T, ReaderInfo, DropReason) // see OnDrop
+	hooks  Hooks[T]                        // see Instrument
+
+	expvar *expvarStats // see Expvar
+
+	logger    *slog.Logger // see Logger
+	logLevels LogLevels
+
+	spill *spiller[T] // see Spill
+
+	persist func(item[T]) // see Durable
 }
 
-// Each item points to the next newer item in the queue.
-type item struct {
-	next *item
-	val  interface{}
+// An item is an entry in the queue.
+// A dead item is one that has been removed from the stream
+// (by compaction or expiry)
+// but not yet from the queue.
+// Readers skip dead items.
+type item[T any] struct {
+	val         T
+	offset      int64
+	dead        bool
+	priority    int               // see Prioritized
+	key         any               // set only when needed (see Compact)
+	written     time.Time         // see Meta
+	headers     map[string]string // see WriteWithMeta
+	err         error             // see WriteError
+	bytesBefore int64             // total size of the items written before this one (see RetainBytes)
+	spill       *spillRef         // where val is, if it has been moved out of memory (see Spill)
 }
 
-// R is the reading end of a one-to-many data channel.
-type R struct {
-	w *W
+// R is the reading end of a one-to-many data channel of items of type T.
+type R[T any] struct {
+	*reader[T]
+
+	disposed bool
+}
+
+// A reader is the state of an R.
+// It is shared by the members of a consumer group (see Group).
+type reader[T any] struct {
+	w     *W[T]
+	name  string      // see Name
+	store OffsetStore // see ResumeReader
+
+	handles int // the number of undisposed Rs (and Groups) referring to this
+	index   int // the index of this reader in w.readers, or -1 if it is not there
 
-	// Points to a pointer to the next item the reader will return.
-	// This is nil for a new reader.
-	// The first time Write is called after a particular reader is created,
-	// this gets set to a pointer to the new item.
-	// Thereafter,
-	// each time read consumes an item,
-	// this gets set to the address of that item's "next" field.
-	//
-	// (Why a pointer to a pointer?
-	// If the reader consumes the newest item in the queue,
-	// that item's "next" field is nil.
-	// When a new item is added with Write,
-	// that same field is updated to point to it;
-	// so we point to that field in order to see that update.)
-	next **item
+	// The stream offset of the next item this reader will return.
+	// A new reader normally starts at the end of the stream,
+	// so it sees only items written after its creation
+	// (but see Sticky and FromEarliest).
+	pos int64
+
+	maxLag int
+	filter func(T) bool // see Where
+	errors bool         // see ReceiveErrors
+
+	// Items skipped (see Skipped) before the next item to be read,
+	// and before the item most recently read.
+	pendingSkip, skipped int64
+
+	// The offsets of items after pos that this reader has already consumed,
+	// out of order because of their priority (see Prioritized),
+	// or that its filter rejected (see Where).
+	taken map[int64]struct{}
+
+	limiter Limiter // see Throttle
+	drop    bool    // see ThrottleDrop
+
+	// Items read with ReadAck and not yet acknowledged, by offset,
+	// and the offsets of those awaiting redelivery, in order.
+	ackTimeout  time.Duration
+	maxAttempts int
+	unacked     map[int64]*unacked[T]
+	redeliver   []int64
+
+	evicted bool
+	behind  bool // see OnLag
+
+	// The end of the stream (see W.end) when NBRead last found nothing to read,
+	// or -1.
+	// It is reset when the reader's position changes.
+	idleAt atomic.Int64
+
+	// Closed (and cleared) to wake goroutines waiting to read from this reader.
+	// Created only when some goroutine waits (see await).
+	wake chan struct{}
+
+	done       chan struct{} // see Done; created on demand
+	doneClosed bool
 }
 
-// New produces a new multichan writer.
-// Its argument is the zero value that readers will see
-// when reading from a closed multichan,
+// New produces a new multichan writer for items of type T.
+// Readers see the zero value of T
+// when reading from a closed multichan
 // (or when non-blockingly reading from an unready multichan).
-func New(zero interface{}) *W {
-	w := &W{
-		zero:     zero,
-		zerotype: reflect.TypeOf(zero),
+//
+// New panics if an option that depends on the item type
+// (such as RetainBytes or DeadLetter)
+// is for items of another type.
+// NewChecked returns an error instead.
+func New[T any](opts ...Option) *W[T] {
+	w, err := NewChecked[T](opts...)
+	if err != nil {
+		panic(err)
 	}
-	w.cond.L = &w.mu
 	return w
 }
 
+// NewChecked is like New
+// but returns an error wrapping ErrOptionType,
+// instead of panicking,
+// if an option that depends on the item type is for items of another type.
+func NewChecked[T any](opts ...Option) (*W[T], error) {
+	var conf config
+	for _, opt := range opts {
+		opt(&conf)
+	}
+	if conf.clock == nil {
+		conf.clock = systemClock{}
+	}
+	w := &W[T]{
+		waiting:     make(map[*reader[T]]struct{}),
+		clock:       conf.clock,
+		capacity:    conf.capacity,
+		overflow:    conf.overflow,
+		retain:      conf.retain,
+		retainFor:   conf.retainFor,
+		retainBytes: conf.retainBytes,
+		replay:      conf.replay,
+		ttl:         conf.ttl,
+		prioritized: conf.prioritized,
+		autoClose:   conf.autoClose,
+		hold:        conf.hold,
+		lagHigh:     conf.lagHigh,
+		lagLow:      conf.lagLow,
+		onLag:       conf.onLag,
+		logger:      conf.logger,
+		logLevels:   conf.logLevels,
+	}
+	if conf.sizer != nil {
+		sizer, ok := conf.sizer.(func(T) int)
+		if !ok {
+			return nil, fmt.Errorf("RetainBytes size function %T: %w", conf.sizer, ErrOptionType)
+		}
+		w.sizer = sizer
+	}
+	if conf.key != nil {
+		key, ok := conf.key.(func(T) any)
+		if !ok {
+			return nil, fmt.Errorf("Compact key function %T: %w", conf.key, ErrOptionType)
+		}
+		w.key = key
+		w.latest = make(map[any]int64)
+	}
+	if conf.eq != nil {
+		eq, ok := conf.eq.(func(a, b T) bool)
+		if !ok {
+			return nil, fmt.Errorf("DedupConsecutive equality function %T: %w", conf.eq, ErrOptionType)
+		}
+		w.eq = eq
+	}
+	if conf.deadLetter != nil {
+		deadLetter, ok := conf.deadLetter.(func(T, DropReason))
+		if !ok {
+			return nil, fmt.Errorf("DeadLetter function %T: %w", conf.deadLetter, ErrOptionType)
+		}
+		w.deadLetter = deadLetter
+	}
+	if w.retain < w.replay {
+		w.retain = w.replay
+	}
+	if conf.spillCodec != nil {
+		codec, ok := conf.spillCodec.(Codec[T])
+		if !ok {
+			return nil, fmt.Errorf("Spill codec %T: %w", conf.spillCodec, ErrOptionType)
+		}
+		w.spill = &spiller[T]{
+			codec:    codec,
+			dir:      conf.spillDir,
+			maxItems: conf.spillItems,
+			maxBytes: int64(conf.spillBytes),
+		}
+		if conf.spillSize != nil {
+			size, ok := conf.spillSize.(func(T) int)
+			if !ok {
+				return nil, fmt.Errorf("SpillBytes size function %T: %w", conf.spillSize, ErrOptionType)
+			}
+			w.spill.size = size
+		}
+	}
+	if conf.onDrop != nil {
+		onDrop, ok := conf.onDrop.(func(T, ReaderInfo, DropReason))
+		if !ok {
+			return nil, fmt.Errorf("OnDrop function %T: %w", conf.onDrop, ErrOptionType)
+		}
+		w.onDrop = onDrop
+	}
+	if conf.hooks != nil {
+		hooks, ok := conf.hooks.(Hooks[T])
+		if !ok {
+			return nil, fmt.Errorf("Instrument hooks %T: %w", conf.hooks, ErrOptionType)
+		}
+		w.hooks = hooks
+	}
+	if conf.expvar != "" {
+		expvarMu.Lock()
+		defer expvarMu.Unlock()
+		if expvar.Get(conf.expvar) != nil {
+			return nil, fmt.Errorf("Expvar name %q: %w", conf.expvar, ErrExpvarName)
+		}
+		w.expvar = &expvarStats{stats: w.Stats}
+		expvar.Publish(conf.expvar, expvar.Func(w.expvar.value))
+	}
+	return w, nil
+}
+
 // Write adds an item to the multichan.
-// Its type must match
-// (i.e., must be assignable to <https://golang.org/ref/spec#Assignability>)
-// that of the zero value passed to New.
 //
 // Each item written to w remains in an internal queue until the last reader has consumed it.
 // Readers added later to a multichan may miss items added earlier.
-func (w *W) Write(val interface{}) {
+//
+// If w has a capacity (see Capacity) and the queue is full,
+// Write behaves according to the multichan's overflow policy.
+//
+// If w is closed,
+// or is closed while Write is blocked waiting for room,
+// Write returns ErrClosed.
+func (w *W[T]) Write(val T) error {
+	return w.WriteContext(nil, val)
+}
+
+// WriteContext is like Write,
+// but if it blocks waiting for room in the queue
+// (see Capacity and Block),
+// it gives up when its context is canceled,
+// returning the context's error.
+// The context argument may be nil.
+func (w *W[T]) WriteContext(ctx context.Context, val T) error {
+	return w.write(ctx, item[T]{val: val}, w.ttl)
+}
+
+// WriteWithTTL is like Write,
+// but the item expires after the given duration
+// instead of the multichan's default (see TTL).
+// A non-positive duration means the item does not expire.
+func (w *W[T]) WriteWithTTL(val T, ttl time.Duration) error {
+	return w.write(nil, item[T]{val: val}, ttl)
+}
+
+// WriteWithPriority is like Write,
+// but gives the item the specified priority
+// instead of the default of 0.
+// Readers of a prioritized multichan (see Prioritized)
+// receive higher-priority items first.
+// In other multichans the priority is ignored.
+func (w *W[T]) WriteWithPriority(val T, priority int) error {
+	return w.write(nil, item[T]{val: val, priority: priority}, w.ttl)
+}
+
+// write adds it (whose val and optional priority and headers are set) to the queue,
+// to expire after ttl if that is positive.
+func (w *W[T]) write(ctx context.Context, it item[T], ttl time.Duration) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrClosed
+	}
+	if it.err == nil && w.dup(it.val) {
+		return nil
+	}
+	if ok, err := w.makeRoom(ctx); !ok {
+		if err == nil && it.err == nil {
+			w.dropNewest(it.val)
+		}
+		return err
+	}
+
+	w.add(it, ttl)
+	w.wakeReaders()
+
+	return nil
+}
+
+// WriteBatch adds the given items to the multichan, in order,
+// waking readers only once for the whole batch.
+// It is otherwise like calling Write on each item,
+// with the overflow policy (see Capacity) applied to each in turn.
+// If the policy is Block,
+// readers are woken to make room before WriteBatch waits,
+// so they may see part of the batch before the rest is written.
+//
+// If w is closed,
+// or is closed while WriteBatch is blocked waiting for room,
+// WriteBatch returns ErrClosed,
+// and no further items from the batch are written.
+func (w *W[T]) WriteBatch(vals []T) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
-	t := reflect.TypeOf(val)
-	if !t.AssignableTo(w.zerotype) {
-		panic(fmt.Sprintf("cannot write %s to multichan of %s", t, w.zerotype))
+
+	if w.closed {
+		return ErrClosed
+	}
+
+	defer w.wakeReaders()
+
+	for _, val := range vals {
+		if w.dup(val) {
+			continue
+		}
+		if w.full() && w.overflow == Block {
+			w.wakeReaders()
+		}
+		ok, err := w.makeRoom(nil)
+		if err != nil {
+			return err
+		}
+		if ok {
+			w.add(item[T]{val: val}, w.ttl)
+		} else {
+			w.dropNewest(val)
+		}
+	}
+
+	return nil
+}
+
+// makeRoom applies w's overflow policy if the queue is full.
+// It reports whether there is room for another item.
+// If not, the error (if any) says why.
+// Callers must hold w.mu.
+func (w *W[T]) makeRoom(ctx context.Context) (bool, error) {
+	if !w.full() {
+		return true, nil
+	}
+
+	// Items kept only for retention can go first.
+	w.trimTo(w.minReaderPos())
+	if !w.full() {
+		return true, nil
+	}
+
+	switch w.overflow {
+	case Block:
+		for (ctx == nil || ctx.Err() == nil) && w.full() && !w.closed {
+			w.await(ctx)
+		}
+		if w.closed {
+			return false, ErrClosed
+		}
+		if ctx != nil && ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+
+	case DropOldest:
+		w.dropOldest()
+
+	case DropNewest:
+		return false, nil
+
+	case EvictSlowest:
+		for w.full() {
+			if len(w.readers) == 0 {
+				// The queue is full of items held for the first reader (see HoldUntilReader),
+				// and there is no reader to evict.
+				w.dropOldest()
+				continue
+			}
+			w.evictSlowest()
+		}
+	}
+
+	return true, nil
+}
+
+// dropOldest discards the oldest item in the queue
+// to make room for a new one,
+// and moves past it the readers that had not consumed it.
+// Callers must hold w.mu.
+func (w *W[T]) dropOldest() {
+	i := w.first(w.offset)
+	var (
+		it      = w.items.at(i)
+		val     = w.value(it)
+		isErr   = it.err != nil
+		dropped = it.offset
+	)
+	if !isErr {
+		w.sendDeadLetter(val, DroppedOverflow)
+	}
+	w.trimTo(dropped + 1)
+	for len(w.readers) > 0 && w.readers[0].pos <= dropped {
+		r := w.readers[0]
+		if _, ok := r.taken[dropped]; ok {
+			r.skipTo(dropped+1, 0)
+			continue
+		}
+		if !isErr {
+			w.dropFor(r, val, DroppedOverflow)
+		}
+		r.skipTo(dropped+1, 1)
+	}
+}
+
+// TryWrite is like Write but never blocks or drops items
+// (regardless of the overflow policy, see Capacity).
+// Instead it reports false if there is no room in the queue,
+// or if w is closed.
+// Otherwise it adds val to the multichan
+// (unless it is a duplicate, see DedupConsecutive)
+// and reports true.
+func (w *W[T]) TryWrite(val T) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return false
+	}
+	if w.dup(val) {
+		return true
+	}
+	if w.full() {
+		// Items kept only for retention can go first, as in makeRoom.
+		w.trimTo(w.minReaderPos())
+		if w.full() {
+			return false
+		}
+	}
+
+	w.add(item[T]{val: val}, w.ttl)
+	w.wakeReaders()
+
+	return true
+}
+
+// add appends it (whose val and optional priority and headers are set) to the queue,
+// which must have room for it,
+// to expire after ttl if that is positive.
+// Callers must hold w.mu
+// and are responsible for waking readers.
+func (w *W[T]) add(it item[T], ttl time.Duration) {
+	w.insert(it, ttl)
+
+	// Filtering and skipping change the order of w.readers,
+	// so find the readers to update before updating them.
+	if w.numFiltered > 0 || (it.err != nil && w.numErrors < len(w.readers)) {
+		var filtering []*reader[T]
+		for _, r := range w.readers {
+			if r.filter != nil || (it.err != nil && !r.errors) {
+				filtering = append(filtering, r)
+			}
+		}
+		for _, r := range filtering {
+			r.filterFrom(w.items.len() - 1)
+		}
+	}
+
+	var lagging []*reader[T]
+	if w.numMaxLag > 0 {
+		for _, r := range w.readers {
+			if r.maxLag > 0 && r.lag() > r.maxLag {
+				lagging = append(lagging, r)
+			}
+		}
+	}
+	for _, r := range lagging {
+		lag := r.lag()
+		pos := r.fromEnd(r.maxLag)
+		if w.onDrop != nil {
+			for i, end := w.index(r.pos), w.index(pos); i < end; i++ {
+				if it := w.items.at(i); !it.dead && it.err == nil {
+					if _, ok := r.taken[it.offset]; !ok {
+						w.dropFor(r, w.value(it), DroppedSkipped)
+					}
+				}
+			}
+		}
+		r.skipTo(pos, int64(lag-r.maxLag))
+	}
+
+	if w.lagHigh > 0 {
+		for _, r := range w.readers {
+			if !r.behind && r.lag() >= w.lagHigh {
+				r.behind = true
+				w.lagEvent(r.info(), true)
+			}
+		}
+	}
+
+	// Trim in case of skipping readers, an expiring retention window,
+	// or the absence of any readers.
+	w.trim()
+
+	if w.spill != nil {
+		w.spillOver()
+	}
+}
+
+// insert appends it (whose val and optional priority and headers are set) to the queue
+// at stream offset w.next,
+// to expire after ttl if that is positive.
+// Unlike add, it does not update the positions of readers or trim the queue.
+// Callers must hold w.mu.
+func (w *W[T]) insert(it item[T], ttl time.Duration) {
+	val := it.val
+	it.offset = w.next
+	w.next++
+	w.published.Store(w.next)
+	if it.err != nil {
+		w.hasErrors = true
+	} else if w.key != nil {
+		it.key = w.key(val)
+		if prev, ok := w.latest[it.key]; ok && prev < w.scanned {
+			// The item being superseded was retained only because it was the latest with its key.
+			if i := w.index(prev); i < w.items.len() && w.items.at(i).offset == prev {
+				w.kill(i)
+			}
+		}
+		w.latest[it.key] = it.offset
+	}
+	it.written = w.clock.Now()
+	if w.sizer != nil {
+		it.bytesBefore = w.bytes
+		w.bytes += int64(w.sizer(val))
+	}
+	if w.persist != nil {
+		w.persist(it)
+	}
+	w.items.push(it)
+	if w.spill != nil {
+		w.spill.stored(val)
+	}
+	if w.hooks != nil && it.err == nil {
+		w.hooks.OnWrite(val, it.offset)
+	}
+
+	if ttl > 0 {
+		w.setExpiry(it.offset, w.clock.Now().Add(ttl))
 	}
 
-	newItem := &item{val: val}
-	oldHead := w.head
-	w.head = newItem
-	if oldHead != nil {
-		oldHead.next = newItem
+	if w.eq != nil && it.err == nil {
+		w.prev, w.havePrev = val, true
 	}
+}
 
-	for _, r := range w.pendingReaders {
-		r.next = &newItem
+// sendDeadLetter passes val, which is being discarded for the given reason,
+// to w's dead-letter function, if any
+// (see DeadLetter).
+// Callers must hold w.mu.
+func (w *W[T]) sendDeadLetter(val T, reason DropReason) {
+	w.dropped++
+	w.log(w.logLevels.Drop, "multichan item dropped", slog.String("reason", reason.String()))
+	if w.deadLetter != nil {
+		w.deadLetter(val, reason)
 	}
-	w.pendingReaders = nil
+}
 
-	w.cond.Broadcast()
+// lagEvent reports that a reader has fallen behind or recovered
+// (see OnLag).
+// Callers must hold w.mu.
+func (w *W[T]) lagEvent(info ReaderInfo, behind bool) {
+	if behind {
+		w.log(w.logLevels.Lag, "multichan reader behind", readerAttrs(info)...)
+	} else {
+		w.log(w.logLevels.Lag, "multichan reader recovered", readerAttrs(info)...)
+	}
+	if w.onLag != nil {
+		w.onLag(info, behind)
+	}
+}
+
+// readHook reports the reading of it by r to w's hooks, if any
+// (see Instrument).
+// Callers must hold w.mu.
+func (w *W[T]) readHook(r *reader[T], it item[T]) {
+	if w.hooks != nil {
+		w.hooks.OnRead(it.val, it.offset, r.info())
+	}
+}
+
+// dropNewest discards val, which is being written,
+// under the DropNewest overflow policy.
+// Callers must hold w.mu.
+func (w *W[T]) dropNewest(val T) {
+	w.sendDeadLetter(val, DroppedOverflow)
+	if w.onDrop != nil {
+		for _, r := range w.readers {
+			w.dropFor(r, val, DroppedOverflow)
+		}
+	}
+}
+
+// dropFor reports that val is discarded without being delivered to r
+// (see OnDrop).
+// Callers must hold w.mu.
+func (w *W[T]) dropFor(r *reader[T], val T, reason DropReason) {
+	if w.onDrop != nil {
+		w.onDrop(val, r.info(), reason)
+	}
+}
+
+// dup tells whether val duplicates the most recently written item
+// (see DedupConsecutive).
+// Callers must hold w.mu.
+func (w *W[T]) dup(val T) bool {
+	return w.eq != nil && w.havePrev && w.eq(w.prev, val)
 }
 
 // Close closes the writing end of a multichan,
 // signaling to readers that the stream has ended.
-// Reading past the end of the stream produces the zero value that was passed to New.
-func (w *W) Close() {
+// Reading past the end of the stream produces the zero value of T.
+// Closing a closed multichan has no effect.
+func (w *W[T]) Close() {
+	w.CloseWithError(nil)
+}
+
+// CloseWithError closes the writing end of a multichan,
+// like Close,
+// and records err as the reason the stream ended.
+// Readers can retrieve it with R.Err.
+// If w is already closed, CloseWithError has no effect
+// (and err is ignored).
+func (w *W[T]) CloseWithError(err error) {
 	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
 	w.closed = true
-	w.cond.Broadcast()
-	w.mu.Unlock()
+	w.err = err
+	w.closeEvent(err)
+	if w.spill != nil {
+		w.spill.retire()
+	}
+	w.checkDone()
+	w.broadcast()
+	w.wakeReaders()
+}
+
+// CloseAndWait closes w, like Close,
+// then waits for every one of w's readers
+// to consume the remaining items or go away
+// (see Flush),
+// or for the context to be canceled,
+// in which case it returns the context's error.
+// This is for the graceful shutdown of a producer.
+// The context argument may be nil.
+func (w *W[T]) CloseAndWait(ctx context.Context) error {
+	w.Close()
+	return w.Flush(ctx)
+}
+
+// Closed tells whether w has been closed
+// (with Close or CloseWithError).
+// Readers may still have items to consume from a closed multichan.
+func (w *W[T]) Closed() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.closed
+}
+
+// Flush blocks until every one of w's readers has caught up,
+// consuming all the items written so far,
+// or has gone away
+// (by being disposed of or evicted),
+// or until the context is canceled,
+// in which case it returns the context's error.
+// This gives a producer a synchronization point with its consumers.
+// The context argument may be nil.
+func (w *W[T]) Flush(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.flushers++
+	defer func() { w.flushers-- }()
+
+	for !w.flushed() {
+		if ctx != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		w.await(ctx)
+	}
+	return nil
+}
+
+// flushed tells whether every one of w's readers has caught up
+// (see Flush).
+// Callers must hold w.mu.
+func (w *W[T]) flushed() bool {
+	w.expire()
+	for _, r := range w.readers {
+		if r.nextIndex() < w.items.len() {
+			return false
+		}
+	}
+	return true
+}
+
+// Done returns a channel that is closed when the last of w's readers goes away
+// (by being disposed of or evicted),
+// so that a producer can stop generating items when no one is listening.
+// If w has had readers and currently has none,
+// the channel is already closed.
+// If w has never had readers,
+// the channel is not closed until some reader is added and then goes away.
+//
+// Once closed, the channel stays closed.
+// Calling Done again after new readers have been added
+// produces a new channel for their departure.
+func (w *W[T]) Done() <-chan struct{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.idle == nil {
+		w.idle = make(chan struct{})
+		w.checkIdle()
+	}
+	return w.idle
+}
+
+// readerGone is called when one or more of w's readers goes away.
+// If that was the last,
+// it closes w if w auto-closes (see AutoClose)
+// and signals Done.
+// Callers must hold w.mu.
+func (w *W[T]) readerGone() {
+	if !w.hadReaders || len(w.readers) > 0 {
+		return
+	}
+	if w.autoClose && !w.closed {
+		w.closed = true
+		w.closeEvent(nil)
+		if w.spill != nil {
+			w.spill.retire()
+		}
+		w.trimTo(w.end())
+		w.expiries = nil
+		if w.timer != nil {
+			w.timer.Stop()
+		}
+		w.broadcast()
+	}
+	w.checkIdle()
+}
+
+// checkIdle closes w.idle if w has had readers and has none now
+// (see Done).
+// Callers must hold w.mu.
+func (w *W[T]) checkIdle() {
+	if w.idle != nil && !w.idleClosed && w.hadReaders && len(w.readers) == 0 {
+		close(w.idle)
+		w.idleClosed = true
+	}
 }
 
 // Reader adds a new reader to the multichan and returns it.
 // Readers consume resources in the multichan and should be disposed of (with Dispose) when no longer needed.
-func (w *W) Reader() *R {
+//
+// Reader panics if an option that depends on the item type (such as Where)
+// is for items of another type.
+// W.ReaderAt and W.ResumeReader return an error instead.
+func (w *W[T]) Reader(opts ...ReaderOption) *R[T] {
+	r, err := w.reader(opts)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// reader implements Reader,
+// returning an error wrapping ErrOptionType instead of panicking.
+func (w *W[T]) reader(opts []ReaderOption) (*R[T], error) {
+	var conf readerConfig
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
 	w.mu.Lock()
 	defer w.mu.Unlock()
-	r := &R{w: w}
-	w.pendingReaders = append(w.pendingReaders, r)
-	return r
+	r, err := w.newReader(conf)
+	if err != nil {
+		return nil, err
+	}
+	if conf.fromEarliest {
+		r.setPos(w.offset)
+	}
+	r.filterFrom(w.index(r.pos))
+	w.readerAdded(r.reader)
+	return r, nil
+}
+
+// ReaderAt is like Reader
+// but positions the new reader at the given stream offset
+// (see R.ReadOffset).
+// The offset must be in the range of items the multichan still retains
+// (see R.SetPos);
+// otherwise ReaderAt returns ErrOffsetRange.
+// The FromEarliest option is ignored.
+func (w *W[T]) ReaderAt(offset int64, opts ...ReaderOption) (*R[T], error) {
+	var conf readerConfig
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if offset < w.offset || offset > w.end() {
+		return nil, ErrOffsetRange
+	}
+	r, err := w.newReader(conf)
+	if err != nil {
+		return nil, err
+	}
+	r.setPos(offset)
+	r.filterFrom(w.index(r.pos))
+	w.readerAdded(r.reader)
+	return r, nil
+}
+
+// Len tells how many items w currently retains:
+// those not yet consumed by every reader,
+// plus any kept by the retention policy (see Retain).
+// Expired items (see TTL) are not counted.
+func (w *W[T]) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expire()
+	return w.items.len() - w.dead
+}
+
+// NumReaders tells how many readers w has.
+// A consumer group (see Group) counts as a single reader.
+// Disposed and evicted readers are not counted.
+func (w *W[T]) NumReaders() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.readers)
+}
+
+// WaitForReaders blocks until w has at least n readers
+// (counted as in NumReaders),
+// so that a producer can hold off writing
+// until its expected consumers have attached
+// and will see every item.
+// It returns ErrClosed if w is closed first,
+// or the context's error if the context is canceled first.
+// The context argument may be nil.
+func (w *W[T]) WaitForReaders(ctx context.Context, n int) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for len(w.readers) < n {
+		if w.closed {
+			return ErrClosed
+		}
+		if ctx != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		w.await(ctx)
+	}
+	return nil
+}
+
+// ReaderInfo describes one of a multichan's readers.
+// See W.ReaderInfo.
+type ReaderInfo struct {
+	Name string // the reader's name (see Name)
+	Pos  int64  // the reader's position in the stream (see R.Pos)
+	Lag  int    // the number of items available to the reader (see R.Pending)
+}
+
+// ReaderInfo returns a description of each of w's readers,
+// in no particular order.
+// A consumer group (see Group) is described as a single reader.
+func (w *W[T]) ReaderInfo() []ReaderInfo {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.expire()
+	return w.readerInfo()
+}
+
+// readerInfo implements ReaderInfo.
+// Callers must hold w.mu.
+func (w *W[T]) readerInfo() []ReaderInfo {
+	infos := make([]ReaderInfo, 0, len(w.readers))
+	for _, r := range w.readers {
+		infos = append(infos, r.info())
+	}
+	return infos
+}
+
+// info describes r.
+// Callers must hold r.w.mu.
+func (r *reader[T]) info() ReaderInfo {
+	return ReaderInfo{Name: r.name, Pos: r.pos, Lag: r.lag()}
+}
+
+// lag is the number of items available to r.
+// Callers must hold r.w.mu.
+func (r *reader[T]) lag() int {
+	return r.w.live(r.w.index(r.pos)) - len(r.taken)
+}
+
+// newReader adds a new reader to w,
+// positioned at the end of the stream
+// (less any items w replays for new readers).
+// It returns an error wrapping ErrOptionType
+// if an option in conf is for items of another type.
+// Callers must hold w.mu.
+func (w *W[T]) newReader(conf readerConfig) (*R[T], error) {
+	var filter func(T) bool
+	if conf.filter != nil {
+		var ok bool
+		filter, ok = conf.filter.(func(T) bool)
+		if !ok {
+			return nil, fmt.Errorf("Where predicate %T: %w", conf.filter, ErrOptionType)
+		}
+	}
+
+	pos := w.end()
+	if w.hold && !w.hadReaders {
+		pos = w.offset
+	} else if w.replay > 0 && w.items.len() > 0 {
+		pos = w.items.at(w.liveFromEnd(w.replay)).offset
+	}
+	r := &reader[T]{
+		w:       w,
+		handles: 1,
+		pos:     pos,
+		maxLag:  conf.maxLag,
+		filter:  filter,
+		errors:  conf.errors,
+		limiter: conf.limiter,
+		drop:    conf.drop,
+
+		ackTimeout:  conf.ackTimeout,
+		maxAttempts: conf.maxAttempts,
+		name:        conf.name,
+	}
+	r.idleAt.Store(-1)
+	heap.Push(&w.readers, r)
+	w.countReader(r, 1)
+	w.hadReaders = true
+	w.broadcast() // see WaitForReaders
+	if w.idleClosed {
+		w.idle, w.idleClosed = nil, false
+	}
+	return &R[T]{reader: r}, nil
+}
+
+// countReader adds delta to the counts of readers
+// with the options that r has
+// (see numFiltered, numErrors, and numMaxLag).
+// Callers must hold w.mu.
+func (w *W[T]) countReader(r *reader[T], delta int) {
+	if r.filter != nil {
+		w.numFiltered += delta
+	}
+	if r.errors {
+		w.numErrors += delta
+	}
+	if r.maxLag > 0 {
+		w.numMaxLag += delta
+	}
+}
+
+// readerAdded reports the addition of r to w's hooks and logger, if any
+// (see Instrument and Logger).
+// Callers must hold w.mu.
+func (w *W[T]) readerAdded(r *reader[T]) {
+	if w.hooks == nil && w.logger == nil {
+		return
+	}
+	info := r.info()
+	w.log(w.logLevels.Readers, "multichan reader added", readerAttrs(info)...)
+	if w.hooks != nil {
+		w.hooks.OnReaderAdded(info)
+	}
+}
+
+// readerDisposed reports the removal of r from w to w's hooks and logger, if any
+// (see Instrument and Logger).
+// Callers must hold w.mu.
+func (w *W[T]) readerDisposed(r *reader[T]) {
+	if w.hooks == nil && w.logger == nil {
+		return
+	}
+	info := r.info()
+	w.log(w.logLevels.Readers, "multichan reader disposed", readerAttrs(info)...)
+	if w.hooks != nil {
+		w.hooks.OnReaderDisposed(info)
+	}
+}
+
+// closeEvent reports the closing of w,
+// with the given error,
+// to w's hooks and logger, if any,
+// and to the goroutines waiting on w.closedCh (see Context).
+// Callers must hold w.mu.
+func (w *W[T]) closeEvent(err error) {
+	if w.closedCh != nil {
+		close(w.closedCh)
+	}
+	if err != nil {
+		w.log(w.logLevels.Close, "multichan closed", slog.String("error", err.Error()))
+	} else {
+		w.log(w.logLevels.Close, "multichan closed")
+	}
+	if w.hooks != nil {
+		w.hooks.OnClose(err)
+	}
+	if w.expvar != nil {
+		w.expvar.close(w.stats())
+	}
+}
+
+// await releases w.mu and waits until the next call to broadcast
+// or until ctx (which may be nil) is canceled,
+// then reacquires w.mu.
+// Callers must hold w.mu
+// and must check for cancellation of ctx themselves.
+func (w *W[T]) await(ctx context.Context) {
+	if w.changed == nil {
+		w.changed = make(chan struct{})
+	}
+	w.sleep(ctx, w.changed)
+}
+
+// broadcast wakes all goroutines waiting in W.await.
+// Callers must hold w.mu.
+func (w *W[T]) broadcast() {
+	if w.changed != nil {
+		close(w.changed)
+		w.changed = nil
+	}
+}
+
+// await is like W.await
+// but waits for a call to notify on r
+// (or to wakeReaders on its multichan).
+// Only goroutines reading from r are woken that way,
+// so that a change of interest to one reader
+// does not wake all the others.
+// Callers must hold r.w.mu.
+func (r *reader[T]) await(ctx context.Context) {
+	if r.wake == nil {
+		r.wake = make(chan struct{})
+		r.w.waiting[r] = struct{}{}
+	}
+	r.w.sleep(ctx, r.wake)
+}
+
+// notify wakes the goroutines waiting in r.await.
+// Callers must hold r.w.mu.
+func (r *reader[T]) notify() {
+	if r.wake != nil {
+		close(r.wake)
+		r.wake = nil
+		delete(r.w.waiting, r)
+	}
+}
+
+// wakeReaders wakes all goroutines waiting to read from w
+// (after it adds items or is closed).
+// Only readers with waiting goroutines are visited.
+// Callers must hold w.mu.
+func (w *W[T]) wakeReaders() {
+	for r := range w.waiting {
+		close(r.wake)
+		r.wake = nil
+	}
+	clear(w.waiting)
+}
+
+// sleep releases w.mu and waits until ch is closed
+// or ctx (which may be nil) is canceled,
+// then reacquires w.mu.
+// Callers must hold w.mu.
+func (w *W[T]) sleep(ctx context.Context, ch <-chan struct{}) {
+	w.mu.Unlock()
+	defer w.mu.Lock()
+
+	if ctx == nil {
+		<-ch
+		return
+	}
+	select {
+	case <-ch:
+	case <-ctx.Done():
+	}
+}
+
+// end is the stream offset of the next item to be written.
+// Callers must hold w.mu.
+func (w *W[T]) end() int64 {
+	return w.next
+}
+
+// index returns the index in w.items of the first item at or after stream offset pos,
+// or w.items.len() if there is none.
+// Callers must hold w.mu.
+func (w *W[T]) index(pos int64) int {
+	n := w.items.len()
+	if n == 0 {
+		return 0
+	}
+	if first := w.items.at(0).offset; w.items.at(n-1).offset-first == int64(n-1) {
+		// The items are contiguous.
+		switch {
+		case pos <= first:
+			return 0
+		case pos-first >= int64(n):
+			return n
+		default:
+			return int(pos - first)
+		}
+	}
+	return sort.Search(n, func(i int) bool { return w.items.at(i).offset >= pos })
+}
+
+// first returns the index in w.items of the first live item at or after stream offset pos,
+// or w.items.len() if there is none.
+// Callers must hold w.mu.
+func (w *W[T]) first(pos int64) int {
+	i := w.index(pos)
+	for i < w.items.len() && w.items.at(i).dead {
+		i++
+	}
+	return i
+}
+
+// live counts the live items in w.items from index i on.
+// Callers must hold w.mu.
+func (w *W[T]) live(i int) int {
+	n := w.items.len() - i
+	if w.dead > 0 {
+		for ; i < w.items.len(); i++ {
+			if w.items.at(i).dead {
+				n--
+			}
+		}
+	}
+	return n
+}
+
+// liveFromEnd returns the index in w.items of the nth live item from the end,
+// where n is at least 1,
+// or 0 if there are fewer than n live items.
+// Callers must hold w.mu.
+func (w *W[T]) liveFromEnd(n int) int {
+	if w.dead == 0 {
+		if n > w.items.len() {
+			return 0
+		}
+		return w.items.len() - n
+	}
+	i := w.items.len()
+	for i > 0 && n > 0 {
+		i--
+		if !w.items.at(i).dead {
+			n--
+		}
+	}
+	return i
+}
+
+// full tells whether w is at capacity.
+// Callers must hold w.mu.
+func (w *W[T]) full() bool {
+	return w.capacity > 0 && w.items.len()-w.dead >= w.capacity
+}
+
+// trim discards items that every reader has already consumed
+// and that the retention policy does not require keeping.
+// Callers must hold w.mu.
+func (w *W[T]) trim() {
+	floor := w.minReaderPos()
+	if w.key != nil {
+		w.compact(floor)
+	}
+
+	// Items before index n are eligible for discarding,
+	// unless the retention policy lowers n.
+	n := w.index(floor)
+	if w.key != nil && w.retain == 0 && w.retainFor == 0 && w.retainBytes == 0 {
+		n = 0
+	}
+	if w.retain > 0 {
+		if i := w.liveFromEnd(w.retain); i < n {
+			n = i
+		}
+	}
+	if w.retainFor > 0 {
+		cutoff := w.clock.Now().Add(-w.retainFor)
+		n = sort.Search(n, func(i int) bool {
+			return w.items.at(i).written.After(cutoff)
+		})
+	}
+	if w.retainBytes > 0 {
+		cutoff := w.bytes - int64(w.retainBytes)
+		n = sort.Search(n, func(i int) bool {
+			return w.items.at(i).bytesBefore >= cutoff
+		})
+	}
+	if n < w.items.len() && w.items.at(n).offset < floor {
+		floor = w.items.at(n).offset
+	}
+	w.trimTo(floor)
+
+	if w.dead > 0 && 2*w.dead >= w.items.len() {
+		w.sweep()
+	}
+
+	if w.closed {
+		w.checkDone()
+	}
+	if w.flushers > 0 {
+		// Wake callers of Flush, since some reader may have caught up.
+		w.broadcast()
+	}
+}
+
+// compact kills items that every reader has consumed
+// and that have been superseded by newer items with the same key.
+// The argument is w.minReaderPos().
+// Callers must hold w.mu.
+func (w *W[T]) compact(minpos int64) {
+	if minpos <= w.scanned {
+		return
+	}
+	for i, end := w.index(w.scanned), w.index(minpos); i < end; i++ {
+		if it := w.items.at(i); !it.dead && w.latest[it.key] != it.offset {
+			w.kill(i)
+		}
+	}
+	w.scanned = minpos
+}
+
+// trimTo discards the items before stream offset pos,
+// which must not be greater than the position of any reader.
+// Callers must hold w.mu.
+func (w *W[T]) trimTo(pos int64) {
+	if n := w.index(pos); n > 0 {
+		w.discard(n)
+		if w.capacity > 0 {
+			// Wake any writer waiting for room.
+			w.broadcast()
+		}
+	}
+	if pos > w.offset {
+		w.offset = pos
+	}
+}
+
+// minReaderPos is the lowest position of any of w's readers,
+// or the end of the stream if there are none.
+// Callers must hold w.mu.
+func (w *W[T]) minReaderPos() int64 {
+	if len(w.readers) == 0 {
+		if w.hold && !w.hadReaders {
+			return w.offset
+		}
+		return w.end()
+	}
+	return w.readers[0].pos
+}
+
+// evictSlowest disposes of the readers furthest behind.
+// Callers must hold w.mu.
+func (w *W[T]) evictSlowest() {
+	minpos := w.minReaderPos()
+	for len(w.readers) > 0 && w.readers[0].pos == minpos {
+		r := heap.Pop(&w.readers).(*reader[T])
+		w.countReader(r, -1)
+		w.readerDisposed(r)
+		r.evicted = true
+		r.notify()
+		r.checkDone()
+	}
+	w.readerGone()
+	w.trim()
+}
+
+// discard removes the oldest n items from the queue.
+// Callers must hold w.mu.
+func (w *W[T]) discard(n int) {
+	w.trimmed += int64(n)
+	for i := 0; i < n; i++ {
+		it := w.items.at(i)
+		if it.dead {
+			w.dead--
+			continue
+		}
+		if w.key != nil && w.latest[it.key] == it.offset {
+			delete(w.latest, it.key)
+		}
+		if w.spill != nil {
+			w.forget(it)
+		}
+	}
+	w.items.dropFront(n)
+}
+
+// kill marks the item at index i in w.items as dead.
+// Callers must hold w.mu.
+func (w *W[T]) kill(i int) {
+	it := w.items.at(i)
+	if w.key != nil && w.latest[it.key] == it.offset {
+		delete(w.latest, it.key)
+	}
+	if w.spill != nil {
+		w.forget(it)
+	}
+	for _, r := range w.readers {
+		delete(r.taken, it.offset)
+	}
+	var zero T
+	it.val = zero // allow the garbage collector to reclaim the value
+	it.key = nil
+	it.headers = nil
+	it.dead = true
+	w.dead++
+}
+
+// sweep removes dead items from the queue.
+// Callers must hold w.mu.
+func (w *W[T]) sweep() {
+	var n int
+	for i := 0; i < w.items.len(); i++ {
+		if it := w.items.at(i); !it.dead {
+			*w.items.at(n) = *it
+			n++
+		}
+	}
+	w.trimmed += int64(w.items.len() - n)
+	w.items.truncate(n)
+	w.dead = 0
 }
 
 // Read reads the next item in the multichan.
 // It blocks until an item is ready to read or its context is canceled.
 // If the multichan is closed and the last item has already been consumed,
 // or the context is canceled,
-// this returns the multichan's zero value (see New) and false.
+// this returns the zero value of T and false.
 // Otherwise it returns the next value and true.
 // The context argument may be nil.
-func (r *R) Read(ctx context.Context) (interface{}, bool) {
-	if ctx != nil {
-		done := make(chan struct{})
-		defer close(done)
-
-		go func() {
-			select {
-			case <-ctx.Done():
-				r.w.mu.Lock()
-				r.w.cond.Broadcast()
-				r.w.mu.Unlock()
-
-			case <-done:
-			}
-		}()
+func (r *R[T]) Read(ctx context.Context) (T, bool) {
+	val, _, ok := r.ReadOffset(ctx)
+	return val, ok
+}
+
+// ReadOffset is like Read
+// but also returns the stream offset of the item read.
+// The first item ever written to a multichan is at offset 0,
+// the next at offset 1,
+// and so on.
+func (r *R[T]) ReadOffset(ctx context.Context) (T, int64, bool) {
+	it, ok := r.read(ctx)
+	return it.val, it.offset, ok
+}
+
+// read implements ReadOffset and ReadMeta.
+func (r *R[T]) read(ctx context.Context) (item[T], bool) {
+	if !r.throttle(ctx) {
+		return item[T]{}, false
+	}
+
+	r.w.mu.Lock()
+	defer r.w.mu.Unlock()
+
+	for r.wait(ctx) {
+		if it, ok := r.next(); ok {
+			return it, true
+		}
+	}
+	return item[T]{}, false
+}
+
+// ReadN reads up to n items from the multichan.
+// It blocks until at least one item is ready to read or its context is canceled,
+// then returns all the items ready to read,
+// or the first n of them if there are more than that.
+// A value of n less than 1 means no limit.
+// If the multichan is closed and the last item has already been consumed,
+// or the context is canceled,
+// this returns nil.
+// The context argument may be nil.
+//
+// If r is throttled (see Throttle and ThrottleDrop),
+// ReadN reads no more than one item at a time.
+func (r *R[T]) ReadN(ctx context.Context, n int) []T {
+	if r.limiter != nil {
+		n = 1
+	}
+
+	if !r.throttle(ctx) {
+		return nil
 	}
 
 	r.w.mu.Lock()
 	defer r.w.mu.Unlock()
 
-	for (ctx == nil || ctx.Err() == nil) && !r.w.closed && (r.next == nil || *r.next == nil) {
-		r.w.cond.Wait()
+	var vals []T
+	for len(vals) == 0 && r.wait(ctx) {
+		var skipped int64
+		for n < 1 || len(vals) < n {
+			i := r.nextIndex()
+			if i == r.w.items.len() {
+				break
+			}
+			it := r.take(i)
+			if !r.pass() {
+				r.pendingSkip++
+				if it.err == nil {
+					r.w.dropFor(r.reader, it.val, DroppedSkipped)
+				}
+				continue
+			}
+			if len(vals) == 0 {
+				skipped = r.pendingSkip
+			}
+			vals = append(vals, it.val)
+			if it.err == nil {
+				r.w.readHook(r.reader, it)
+			}
+		}
+		if len(vals) > 0 {
+			r.skipped, r.pendingSkip = skipped, 0
+		}
+		r.w.trim()
 	}
-	if r.next != nil && *r.next != nil {
-		val := (*r.next).val
-		r.next = &(*r.next).next
-		return val, true
+	return vals
+}
+
+// Drain reads items from r until the multichan is closed
+// and the last item has been consumed,
+// or until the context is canceled.
+// It returns the items read
+// along with the context's error if it was canceled,
+// or else r's error (see Err).
+// Drain disposes of r before returning.
+// The context argument may be nil.
+func (r *R[T]) Drain(ctx context.Context) ([]T, error) {
+	defer r.Dispose()
+
+	var vals []T
+	for {
+		batch := r.ReadN(ctx, 0)
+		if batch == nil {
+			break
+		}
+		vals = append(vals, batch...)
+	}
+	if ctx != nil && ctx.Err() != nil {
+		return vals, ctx.Err()
+	}
+	return vals, r.Err()
+}
+
+// wait waits until r has an item to read,
+// or the multichan is closed,
+// or ctx (which may be nil) is canceled.
+// It reports whether r has an item to read.
+// Callers must hold r.w.mu.
+func (r *R[T]) wait(ctx context.Context) bool {
+	for (ctx == nil || ctx.Err() == nil) && !r.w.closed && !r.disposed && !r.evicted && !r.ready() {
+		r.await(ctx)
+	}
+	return r.ready()
+}
+
+// ready tells whether r has an item to read.
+// Callers must hold r.w.mu.
+func (r *R[T]) ready() bool {
+	if r.evicted || r.disposed {
+		return false
+	}
+	r.w.expire()
+	return r.nextIndex() < r.w.items.len()
+}
+
+// nextIndex returns the index in r.w.items of the next item r will read,
+// or r.w.items.len() if there is none.
+// Callers must hold r.w.mu.
+func (r *reader[T]) nextIndex() int {
+	i := r.w.first(r.pos)
+	if !r.w.prioritized {
+		// Skip items the filter rejected (see Where).
+		for len(r.taken) > 0 && i < r.w.items.len() {
+			offset := r.w.items.at(i).offset
+			if _, ok := r.taken[offset]; !ok {
+				break
+			}
+			i = r.w.first(offset + 1)
+		}
+		return i
+	}
+
+	// Find the first of the highest-priority items not yet taken.
+	best := r.w.items.len()
+	for ; i < r.w.items.len(); i++ {
+		it := r.w.items.at(i)
+		if it.dead {
+			continue
+		}
+		if _, ok := r.taken[it.offset]; ok {
+			continue
+		}
+		if best == r.w.items.len() || it.priority > r.w.items.at(best).priority {
+			best = i
+		}
+	}
+	return best
+}
+
+// take consumes and returns the item at index i in r.w.items,
+// which must be the one given by nextIndex.
+// Callers must hold r.w.mu
+// and are responsible for trimming.
+func (r *R[T]) take(i int) item[T] {
+	it := *r.w.items.at(i)
+	it.val = r.w.value(&it)
+	if i != r.w.first(r.pos) {
+		// Consuming out of order.
+		if r.taken == nil {
+			r.taken = make(map[int64]struct{})
+		}
+		r.taken[it.offset] = struct{}{}
+		r.advance()
+		return it
+	}
+	r.setPos(it.offset + 1)
+	r.advance()
+	return it
+}
+
+// advance moves r's position past any items it has already taken out of order
+// (see Prioritized).
+// Callers must hold r.w.mu.
+func (r *reader[T]) advance() {
+	if len(r.taken) == 0 {
+		return
+	}
+	pos := r.pos
+	for {
+		i := r.w.first(pos)
+		if i == r.w.items.len() {
+			break
+		}
+		offset := r.w.items.at(i).offset
+		if _, ok := r.taken[offset]; !ok {
+			break
+		}
+		delete(r.taken, offset)
+		pos = offset + 1
+	}
+	for offset := range r.taken {
+		if offset < pos {
+			delete(r.taken, offset)
+		}
+	}
+	r.setPos(pos)
+}
+
+// filterFrom marks as taken the items from index i in r.w.items onward
+// that r's filter rejects (see Where),
+// and the errors it does not receive (see ReceiveErrors),
+// then advances r past any at its position.
+// Callers must hold r.w.mu.
+func (r *reader[T]) filterFrom(i int) {
+	if r.filter == nil && (r.errors || !r.w.hasErrors) {
+		return
+	}
+	for ; i < r.w.items.len(); i++ {
+		it := r.w.items.at(i)
+		if it.dead || it.offset < r.pos {
+			continue
+		}
+		if _, ok := r.taken[it.offset]; ok {
+			continue
+		}
+		var reject bool
+		if it.err != nil {
+			reject = !r.errors
+		} else if r.filter != nil {
+			reject = !r.filter(r.w.value(it))
+		}
+		if reject {
+			if r.taken == nil {
+				r.taken = make(map[int64]struct{})
+			}
+			r.taken[it.offset] = struct{}{}
+		}
+	}
+	r.advance()
+}
+
+// fromEnd returns the stream offset of the nth item from the end of the stream
+// that r has yet to read,
+// where n is at least 1 and at most r.lag().
+// Callers must hold r.w.mu.
+func (r *reader[T]) fromEnd(n int) int64 {
+	if len(r.taken) == 0 {
+		return r.w.items.at(r.w.liveFromEnd(n)).offset
+	}
+	i := r.w.items.len()
+	for n > 0 {
+		i--
+		it := r.w.items.at(i)
+		if it.dead {
+			continue
+		}
+		if _, ok := r.taken[it.offset]; !ok {
+			n--
+		}
+	}
+	return r.w.items.at(i).offset
+}
+
+// setPos sets r's position,
+// keeping its multichan's readers in order.
+// Callers must hold r.w.mu.
+func (r *reader[T]) setPos(pos int64) {
+	if pos == r.pos {
+		return
+	}
+	r.pos = pos
+	r.idleAt.Store(-1)
+	if r.index >= 0 {
+		heap.Fix(&r.w.readers, r.index)
+	}
+	if r.behind {
+		if lag := r.lag(); lag <= r.w.lagLow {
+			r.behind = false
+			r.w.lagEvent(ReaderInfo{Name: r.name, Pos: r.pos, Lag: lag}, false)
+		}
 	}
-	return r.w.zero, false
 }
 
 // NBRead does a non-blocking read on the multichan.
 // If the multichan is closed and the last item has already been consumed,
 // or if no next item is ready to read,
-// this returns the multichan's zero value (see New) and false.
+// this returns the zero value of T and false.
 // Otherwise it returns the next value and true.
-func (r *R) NBRead() (interface{}, bool) {
+//
+// Polling an idle reader with NBRead does not contend with writers for the multichan's lock.
+func (r *R[T]) NBRead() (T, bool) {
+	var zero T
+
+	// Fast path: nothing has been written since r last found nothing to read.
+	if r.idleAt.Load() == r.w.published.Load() {
+		return zero, false
+	}
+
+	r.w.mu.Lock()
+	defer r.w.mu.Unlock()
+	for {
+		if !r.ready() {
+			r.idleAt.Store(r.w.end())
+			return zero, false
+		}
+		if r.limiter != nil && !r.drop && !r.limiter.Allow() {
+			return zero, false
+		}
+		if it, ok := r.next(); ok {
+			return it.val, true
+		}
+	}
+}
+
+// Peek returns the next item in the multichan without consuming it.
+// It does not block.
+// If no next item is ready to read,
+// this returns the zero value of T and false.
+// Otherwise it returns the next value and true,
+// and the next call to Read, NBRead, or Peek will return the same value.
+func (r *R[T]) Peek() (T, bool) {
+	r.w.mu.Lock()
+	defer r.w.mu.Unlock()
+	if r.ready() {
+		return r.w.value(r.w.items.at(r.nextIndex())), true
+	}
+	var zero T
+	return zero, false
+}
+
+// PeekContext is like Peek,
+// but blocks until an item is ready to read or its context is canceled,
+// like Read.
+// The context argument may be nil.
+func (r *R[T]) PeekContext(ctx context.Context) (T, bool) {
+	r.w.mu.Lock()
+	defer r.w.mu.Unlock()
+
+	if r.wait(ctx) {
+		return r.w.value(r.w.items.at(r.nextIndex())), true
+	}
+	var zero T
+	return zero, false
+}
+
+// next consumes the next item for r,
+// which must exist (see ready),
+// and returns its value and stream offset.
+// It returns false if the item was dropped by throttling (see ThrottleDrop).
+// Callers must hold r.w.mu.
+func (r *R[T]) next() (item[T], bool) {
+	it := r.take(r.nextIndex())
+	if !r.pass() {
+		r.pendingSkip++
+		if it.err == nil {
+			r.w.dropFor(r.reader, it.val, DroppedSkipped)
+		}
+		r.w.trim()
+		return item[T]{}, false
+	}
+	r.skipped, r.pendingSkip = r.pendingSkip, 0
+	if it.err == nil {
+		r.w.readHook(r.reader, it)
+	}
+	r.w.trim()
+	return it, true
+}
+
+// throttle waits until r's limiter permits another read,
+// if r is throttled with Throttle.
+// It reports false if ctx (which may be nil) is canceled first.
+// Callers must not hold r.w.mu.
+func (r *R[T]) throttle(ctx context.Context) bool {
+	if r.limiter == nil || r.drop {
+		return true
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return r.limiter.Wait(ctx) == nil
+}
+
+// pass tells whether r may deliver an item it has just consumed,
+// which is false if r is throttled with ThrottleDrop
+// and its limiter does not currently permit another read.
+// Callers must hold r.w.mu.
+func (r *R[T]) pass() bool {
+	return r.limiter == nil || !r.drop || r.limiter.Allow()
+}
+
+// skipTo advances r's position to pos without reading the n items before it.
+// Callers must hold r.w.mu.
+func (r *reader[T]) skipTo(pos, n int64) {
+	r.setPos(pos)
+	r.pendingSkip += n
+	r.advance()
+}
+
+// Skip discards up to n of the items ready for r to read,
+// without blocking,
+// and returns the number discarded.
+func (r *R[T]) Skip(n int) int {
+	r.w.mu.Lock()
+	defer r.w.mu.Unlock()
+	if r.evicted || r.disposed || n < 1 {
+		return 0
+	}
+	var skipped int
+	for ; skipped < n; skipped++ {
+		i := r.nextIndex()
+		if i == r.w.items.len() {
+			break
+		}
+		r.take(i)
+	}
+	r.w.trim()
+	return skipped
+}
+
+// SkipToLatest discards all the items ready for r to read,
+// so that the next one it reads will be the next one written.
+// It returns the number discarded.
+func (r *R[T]) SkipToLatest() int {
+	r.w.mu.Lock()
+	defer r.w.mu.Unlock()
+	if r.evicted || r.disposed {
+		return 0
+	}
+	n := r.lag()
+	r.setPos(r.w.end())
+	r.taken = nil
+	r.w.trim()
+	return n
+}
+
+// Pending tells how many items r can read without blocking.
+// For a member of a consumer group (see W.Group),
+// this is the number available to the group as a whole.
+func (r *R[T]) Pending() int {
 	r.w.mu.Lock()
 	defer r.w.mu.Unlock()
-	if r.next != nil && *r.next != nil {
-		val := (*r.next).val
-		r.next = &(*r.next).next
-		return val, true
+	if r.evicted || r.disposed {
+		return 0
 	}
-	return r.w.zero, false
+	r.w.expire()
+	return r.lag()
+}
+
+// Pos returns r's position in the stream:
+// the offset of the next item it will read
+// (see ReadOffset).
+func (r *R[T]) Pos() int64 {
+	r.w.mu.Lock()
+	defer r.w.mu.Unlock()
+	return r.pos
+}
+
+// SetPos sets r's position in the stream
+// (see Pos).
+// The offset must be in the range of items the multichan still retains,
+// which begins with the oldest item not yet consumed by every reader
+// and ends with the next item to be written;
+// otherwise SetPos returns ErrOffsetRange.
+func (r *R[T]) SetPos(offset int64) error {
+	r.w.mu.Lock()
+	defer r.w.mu.Unlock()
+	if r.evicted {
+		return ErrEvicted
+	}
+	if r.disposed {
+		return ErrDisposed
+	}
+	if offset < r.w.offset || offset > r.w.end() {
+		return ErrOffsetRange
+	}
+	r.setPos(offset)
+	r.pendingSkip = 0
+	r.taken = nil
+	r.filterFrom(r.w.index(offset))
+	r.w.trim()
+	return nil
+}
+
+// Skipped tells how many items r skipped over,
+// because of the DropOldest overflow policy or the MaxLag or ThrottleDrop reader options,
+// immediately before the item most recently returned by Read or NBRead
+// (or the first of the items most recently returned by ReadN).
+func (r *R[T]) Skipped() int64 {
+	r.w.mu.Lock()
+	defer r.w.mu.Unlock()
+	return r.skipped
+}
+
+// Err returns the reason r stopped producing items, if any.
+// It is ErrEvicted if r was disposed of by the EvictSlowest overflow policy,
+// or the error passed to CloseWithError if the multichan was closed that way,
+// or ErrDisposed if r was disposed of before the multichan was closed.
+// Otherwise
+// (including when the multichan was closed with Close)
+// it is nil.
+func (r *R[T]) Err() error {
+	r.w.mu.Lock()
+	defer r.w.mu.Unlock()
+	if r.evicted {
+		return ErrEvicted
+	}
+	if r.disposed && !r.w.closed {
+		return ErrDisposed
+	}
+	return r.w.err
 }
 
 // Dispose removes r from its multichan, freeing up resources.
-// It is an error to make further method calls on r after Dispose.
-func (r *R) Dispose() {
-	// Do nothing. (An earlier implementation had code here.)
+// Any call blocked reading from r returns as if the stream had ended.
+// After Dispose,
+// reads from r fail,
+// Skip and SkipToLatest skip nothing,
+// SetPos returns ErrDisposed,
+// and so does Err
+// (unless the multichan was closed first).
+// Calling Dispose again has no effect.
+// This makes it safe to dispose of r in deferred cleanup code
+// even if it may have been disposed of already.
+func (r *R[T]) Dispose() {
+	r.w.mu.Lock()
+	defer r.w.mu.Unlock()
+	if r.disposed {
+		return
+	}
+	r.disposed = true
+	r.release()
+	r.notify()
+}
+
+// Done returns a channel that is closed when r can read no further items:
+// when the multichan is closed and r has consumed the last item
+// (and acknowledged any read with ReadAck),
+// or when r is disposed of.
+// This allows the end of the stream to be detected in a select statement.
+// For a member of a consumer group (see W.Group),
+// the channel is closed when the group as a whole is done.
+func (r *R[T]) Done() <-chan struct{} {
+	r.w.mu.Lock()
+	defer r.w.mu.Unlock()
+	if r.done == nil {
+		r.done = make(chan struct{})
+		r.checkDone()
+	}
+	return r.done
+}
+
+// checkDone closes r.done if r can read no further items
+// (see Done).
+// Callers must hold r.w.mu.
+func (r *reader[T]) checkDone() {
+	if r.done == nil || r.doneClosed {
+		return
+	}
+	if r.evicted || r.handles == 0 || (r.w.closed && r.nextIndex() == r.w.items.len() && len(r.unacked) == 0) {
+		close(r.done)
+		r.doneClosed = true
+	}
+}
+
+// checkDone calls checkDone on each of w's readers.
+// Callers must hold w.mu.
+func (w *W[T]) checkDone() {
+	for _, r := range w.readers {
+		r.checkDone()
+	}
+}
+
+// Closed tells whether r has been disposed of,
+// either with Dispose
+// or by the EvictSlowest overflow policy.
+func (r *R[T]) Closed() bool {
+	r.w.mu.Lock()
+	defer r.w.mu.Unlock()
+	return r.disposed || r.evicted
+}
+
+// release drops one handle on r,
+// removing r from its multichan if that was the last.
+// Callers must hold r.w.mu.
+func (r *reader[T]) release() {
+	r.handles--
+	if r.handles > 0 {
+		return
+	}
+	for _, u := range r.unacked {
+		if u.timer != nil {
+			u.timer.Stop()
+		}
+	}
+	if r.index >= 0 { // not already evicted
+		heap.Remove(&r.w.readers, r.index)
+		r.w.countReader(r, -1)
+		r.w.readerDisposed(r)
+	}
+	r.checkDone()
+	r.w.readerGone()
+	r.w.trim()
 }
@@ -1,12 +1,17 @@
 package multichan
 
 import (
+	"context"
+	"errors"
 	"reflect"
+	"sort"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestSimple(t *testing.T) {
-	w := New(0)
+	w := New[int]()
 	r := w.Reader()
 	var got []int
 	ready := make(chan struct{})
@@ -16,7 +21,7 @@ func TestSimple(t *testing.T) {
 			if !ok {
 				break
 			}
-			got = append(got, g.(int))
+			got = append(got, g)
 		}
 		close(ready)
 	}()
@@ -35,7 +40,7 @@ func TestSimple(t *testing.T) {
 
 func TestTwo(t *testing.T) {
 	var (
-		w      = New(0)
+		w      = New[int]()
 		r1     = w.Reader()
 		r2     = w.Reader()
 		got1   []int
@@ -50,7 +55,7 @@ func TestTwo(t *testing.T) {
 			if !ok {
 				break
 			}
-			got1 = append(got1, g.(int))
+			got1 = append(got1, g)
 		}
 		close(ready1)
 	}()
@@ -61,7 +66,7 @@ func TestTwo(t *testing.T) {
 			if !ok {
 				break
 			}
-			got2 = append(got2, g.(int))
+			got2 = append(got2, g)
 		}
 		close(ready2)
 	}()
@@ -83,7 +88,7 @@ func TestTwo(t *testing.T) {
 }
 
 func TestNBRead(t *testing.T) {
-	w := New(0)
+	w := New[int]()
 	r := w.Reader()
 	got, ok := r.NBRead()
 	if ok {
@@ -99,30 +104,13 @@ func TestNBRead(t *testing.T) {
 	}
 }
 
-func TestTypecheck(t *testing.T) {
-	var ok bool
-	func() {
-		defer func() {
-			if r := recover(); r != nil {
-				ok = true
-			}
-		}()
-		w := New(0)
-		w.Write("foo")
-	}()
-	if !ok {
-		t.Errorf("typechecking failed")
-	}
-}
-
 func Test100(t *testing.T) {
-	w := New(0)
+	w := New[int]()
 	r := w.Reader()
 
 	go func() {
 		for i := 1; i <= 100; i++ {
-			g, ok := r.Read(nil)
-			got := g.(int)
+			got, ok := r.Read(nil)
 			if !ok {
 				t.Error("unexpected end of stream")
 			} else if got != i {
@@ -142,20 +130,1520 @@ func Test100(t *testing.T) {
 }
 
 func TestTrim(t *testing.T) {
-	w := New(0)
+	w := New[int]()
+
+	w.Write(1)
+	r := w.Reader()
+	w.Write(2)
+	got, ok := r.Read(nil)
+	if !ok {
+		t.Fatal("unexpected end of stream")
+	}
+	if got != 2 {
+		t.Errorf("got %d, want 2", got)
+	}
+}
+
+func TestHoldUntilReader(t *testing.T) {
+	w := New[int](HoldUntilReader())
+
+	w.WriteBatch([]int{1, 2})
+	r := w.Reader()
+	w.Write(3)
+	if got := r.ReadN(nil, 0); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("got %v, want [1 2 3]", got)
+	}
+
+	// Later readers start at the end of the stream as usual.
+	r.Dispose()
+	w.Write(4)
+	r = w.Reader()
+	w.Write(5)
+	if got, ok := r.NBRead(); !ok || got != 5 {
+		t.Errorf("got %d, %v; want 5, true", got, ok)
+	}
+
+	// Held items count against capacity.
+	cases := []struct {
+		overflow Overflow
+		want     []int
+	}{
+		{overflow: DropOldest, want: []int{2, 3}},
+		{overflow: DropNewest, want: []int{1, 2}},
+		{overflow: EvictSlowest, want: []int{2, 3}},
+	}
+	for _, c := range cases {
+		var dropped []int
+		w = New[int](HoldUntilReader(), Capacity(2, c.overflow), DeadLetter(func(val int, _ DropReason) {
+			dropped = append(dropped, val)
+		}))
+		w.WriteBatch([]int{1, 2, 3})
+		if got := w.Reader().ReadN(nil, 0); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("overflow %d: got %v, want %v", c.overflow, got, c.want)
+		}
+		if len(dropped) != 1 {
+			t.Errorf("overflow %d: got %v dead-lettered, want one item", c.overflow, dropped)
+		}
+	}
+
+	// With Block, the write waits for a reader to consume a held item.
+	w = New[int](HoldUntilReader(), Capacity(2, Block))
+	w.WriteBatch([]int{1, 2})
+	errch := make(chan error)
+	go func() { errch <- w.Write(3) }()
+	select {
+	case err := <-errch:
+		t.Fatalf("write did not block (error %v)", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	r = w.Reader()
+	if got, ok := r.NBRead(); !ok || got != 1 {
+		t.Errorf("got %d, %v; want 1, true", got, ok)
+	}
+	if err := <-errch; err != nil {
+		t.Fatal(err)
+	}
+	if got := r.ReadN(nil, 0); !reflect.DeepEqual(got, []int{2, 3}) {
+		t.Errorf("got %v, want [2 3]", got)
+	}
+}
+
+func TestCloseWithError(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	w := New[int]()
+	r := w.Reader()
+	w.Write(1)
+	w.CloseWithError(errBoom)
+
+	got, ok := r.Read(nil)
+	if !ok {
+		t.Fatal("unexpected end of stream")
+	}
+	if got != 1 {
+		t.Errorf("got %d, want 1", got)
+	}
+	if _, ok = r.Read(nil); ok {
+		t.Fatal("unexpected non-end of stream")
+	}
+	if err := r.Err(); !errors.Is(err, errBoom) {
+		t.Errorf("got error %v, want %v", err, errBoom)
+	}
+
+	w = New[int]()
+	r = w.Reader()
+	w.Close()
+	if _, ok = r.Read(nil); ok {
+		t.Fatal("unexpected non-end of stream")
+	}
+	if err := r.Err(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestWriteClosed(t *testing.T) {
+	w := New[int]()
+	r := w.Reader()
+	if err := w.Write(1); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if err := w.Write(2); !errors.Is(err, ErrClosed) {
+		t.Errorf("got error %v, want %v", err, ErrClosed)
+	}
+
+	got, ok := r.Read(nil)
+	if !ok {
+		t.Fatal("unexpected end of stream")
+	}
+	if got != 1 {
+		t.Errorf("got %d, want 1", got)
+	}
+	if _, ok = r.Read(nil); ok {
+		t.Fatal("unexpected non-end of stream")
+	}
+}
+
+func TestDropOldest(t *testing.T) {
+	w := New[int](Capacity(2, DropOldest))
+	r1 := w.Reader()
+	r2 := w.Reader()
+
+	w.Write(1)
+	w.Write(2)
+	w.Write(3)
+	w.Close()
+
+	for i, r := range []*R[int]{r1, r2} {
+		var got []int
+		for {
+			g, ok := r.Read(nil)
+			if !ok {
+				break
+			}
+			got = append(got, g)
+		}
+		if !reflect.DeepEqual(got, []int{2, 3}) {
+			t.Errorf("reader %d: got %v, want [2, 3]", i+1, got)
+		}
+	}
+}
+
+func TestDropNewest(t *testing.T) {
+	w := New[int](Capacity(2, DropNewest))
+	r := w.Reader()
+
+	w.Write(1)
+	w.Write(2)
+	w.Write(3)
+	w.Close()
+
+	var got []int
+	for {
+		g, ok := r.Read(nil)
+		if !ok {
+			break
+		}
+		got = append(got, g)
+	}
+	if !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("got %v, want [1, 2]", got)
+	}
+}
+
+func TestEvictSlowest(t *testing.T) {
+	w := New[int](Capacity(2, EvictSlowest))
+	slow := w.Reader()
+	fast := w.Reader()
 
 	w.Write(1)
+	if _, ok := fast.NBRead(); !ok {
+		t.Fatal("unexpected failure from NBRead")
+	}
+	w.Write(2)
+	w.Write(3)
+
+	if _, ok := slow.NBRead(); ok {
+		t.Error("unexpected success from NBRead on evicted reader")
+	}
+	if err := slow.Err(); !errors.Is(err, ErrEvicted) {
+		t.Errorf("got error %v, want %v", err, ErrEvicted)
+	}
+
+	for want := 2; want <= 3; want++ {
+		got, ok := fast.NBRead()
+		if !ok {
+			t.Fatal("unexpected failure from NBRead")
+		}
+		if got != want {
+			t.Errorf("got %d, want %d", got, want)
+		}
+	}
+	if err := fast.Err(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// Disposing of an evicted reader is harmless.
+	slow.Dispose()
+	if n := w.NumReaders(); n != 1 {
+		t.Errorf("got %d readers, want 1", n)
+	}
+}
+
+func TestBlock(t *testing.T) {
+	w := New[int](Capacity(2, Block))
 	r := w.Reader()
+
+	w.Write(1)
 	w.Write(2)
+
+	wrote := make(chan struct{})
+	go func() {
+		w.Write(3)
+		close(wrote)
+	}()
+
+	select {
+	case <-wrote:
+		t.Fatal("Write did not block on a full multichan")
+	case <-time.After(10 * time.Millisecond):
+	}
+
 	got, ok := r.Read(nil)
 	if !ok {
 		t.Fatal("unexpected end of stream")
 	}
-	gotInt, ok := got.(int)
+	if got != 1 {
+		t.Errorf("got %d, want 1", got)
+	}
+
+	<-wrote
+
+	for want := 2; want <= 3; want++ {
+		got, ok = r.NBRead()
+		if !ok {
+			t.Fatal("unexpected failure from NBRead")
+		}
+		if got != want {
+			t.Errorf("got %d, want %d", got, want)
+		}
+	}
+}
+
+func TestWriteContext(t *testing.T) {
+	w := New[int](Capacity(1, Block))
+	r := w.Reader()
+
+	if err := w.WriteContext(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := w.WriteContext(ctx, 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	got, ok := r.NBRead()
+	if !ok {
+		t.Fatal("unexpected failure from NBRead")
+	}
+	if got != 1 {
+		t.Errorf("got %d, want 1", got)
+	}
+	if _, ok = r.NBRead(); ok {
+		t.Error("unexpected success from NBRead")
+	}
+}
+
+func TestTryWrite(t *testing.T) {
+	for _, overflow := range []Overflow{Block, DropOldest, DropNewest, EvictSlowest} {
+		w := New[int](Capacity(1, overflow))
+		r := w.Reader()
+
+		if !w.TryWrite(1) {
+			t.Fatalf("overflow %d: unexpected failure from TryWrite", overflow)
+		}
+		if w.TryWrite(2) {
+			t.Errorf("overflow %d: unexpected success from TryWrite on full multichan", overflow)
+		}
+
+		got, ok := r.NBRead()
+		if !ok {
+			t.Fatalf("overflow %d: unexpected failure from NBRead", overflow)
+		}
+		if got != 1 {
+			t.Errorf("overflow %d: got %d, want 1", overflow, got)
+		}
+
+		w.Close()
+		if w.TryWrite(3) {
+			t.Errorf("overflow %d: unexpected success from TryWrite on closed multichan", overflow)
+		}
+	}
+
+	// Items kept only for retention do not count against capacity.
+	w := New[int](Capacity(1, Block), Retain(1))
+	if !w.TryWrite(1) {
+		t.Fatal("unexpected failure from TryWrite")
+	}
+	if !w.TryWrite(2) {
+		t.Error("unexpected failure from TryWrite with only a retained item")
+	}
+}
+
+func TestWriteBatch(t *testing.T) {
+	w := New[int]()
+	r := w.Reader()
+
+	if err := w.WriteBatch([]int{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if err := w.WriteBatch([]int{4}); !errors.Is(err, ErrClosed) {
+		t.Errorf("got error %v, want %v", err, ErrClosed)
+	}
+
+	var got []int
+	for {
+		g, ok := r.Read(nil)
+		if !ok {
+			break
+		}
+		got = append(got, g)
+	}
+	if !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("got %v, want [1, 2, 3]", got)
+	}
+}
+
+func TestWriteBatchBlock(t *testing.T) {
+	w := New[int](Capacity(2, Block))
+	r := w.Reader()
+
+	var (
+		got   []int
+		ready = make(chan struct{})
+	)
+	go func() {
+		for {
+			g, ok := r.Read(nil)
+			if !ok {
+				break
+			}
+			got = append(got, g)
+		}
+		close(ready)
+	}()
+
+	if err := w.WriteBatch([]int{1, 2, 3, 4, 5}); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	<-ready
+
+	if !reflect.DeepEqual(got, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("got %v, want [1, 2, 3, 4, 5]", got)
+	}
+}
+
+func TestReadN(t *testing.T) {
+	w := New[int]()
+	r := w.Reader()
+
+	w.WriteBatch([]int{1, 2, 3, 4, 5})
+
+	got := r.ReadN(nil, 2)
+	if !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("got %v, want [1, 2]", got)
+	}
+	got = r.ReadN(nil, 0)
+	if !reflect.DeepEqual(got, []int{3, 4, 5}) {
+		t.Errorf("got %v, want [3, 4, 5]", got)
+	}
+
+	w.Close()
+	if got = r.ReadN(nil, 0); got != nil {
+		t.Errorf("got %v, want nil", got)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	w := New[int]()
+	r := w.Reader()
+
+	w.WriteBatch([]int{1, 2, 3})
+	w.Close()
+
+	got, err := r.Drain(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("got %v, want [1, 2, 3]", got)
+	}
+
+	w = New[int]()
+	r = w.Reader()
+	w.Write(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	got, err = r.Drain(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("got %v, want [1]", got)
+	}
+}
+
+func TestPeek(t *testing.T) {
+	w := New[int]()
+	r := w.Reader()
+
+	if _, ok := r.Peek(); ok {
+		t.Error("unexpected success from Peek")
+	}
+
+	go w.Write(1)
+
+	got, ok := r.PeekContext(context.Background())
+	if !ok {
+		t.Fatal("unexpected failure from PeekContext")
+	}
+	if got != 1 {
+		t.Errorf("got %d, want 1", got)
+	}
+	got, ok = r.Peek()
+	if !ok {
+		t.Fatal("unexpected failure from Peek")
+	}
+	if got != 1 {
+		t.Errorf("got %d, want 1", got)
+	}
+	got, ok = r.NBRead()
+	if !ok {
+		t.Fatal("unexpected failure from NBRead")
+	}
+	if got != 1 {
+		t.Errorf("got %d, want 1", got)
+	}
+	if _, ok = r.Peek(); ok {
+		t.Error("unexpected success from Peek")
+	}
+}
+
+func TestSkip(t *testing.T) {
+	w := New[int]()
+	r := w.Reader()
+
+	w.WriteBatch([]int{1, 2, 3, 4, 5})
+
+	if n := r.Skip(2); n != 2 {
+		t.Errorf("skipped %d, want 2", n)
+	}
+	got, ok := r.NBRead()
 	if !ok {
-		t.Fatalf("unexpected %T on read, want int", got)
+		t.Fatal("unexpected failure from NBRead")
+	}
+	if got != 3 {
+		t.Errorf("got %d, want 3", got)
+	}
+
+	if n := r.SkipToLatest(); n != 2 {
+		t.Errorf("skipped %d, want 2", n)
+	}
+	if _, ok = r.NBRead(); ok {
+		t.Error("unexpected success from NBRead")
+	}
+
+	w.Write(6)
+	if n := r.Skip(10); n != 1 {
+		t.Errorf("skipped %d, want 1", n)
+	}
+}
+
+func TestSetPos(t *testing.T) {
+	w := New[int]()
+	r1 := w.Reader()
+	r2 := w.Reader()
+
+	w.WriteBatch([]int{1, 2, 3})
+
+	got, offset, ok := r1.ReadOffset(nil)
+	if !ok {
+		t.Fatal("unexpected end of stream")
+	}
+	if got != 1 || offset != 0 {
+		t.Errorf("got %d at offset %d, want 1 at offset 0", got, offset)
+	}
+	r1.Skip(1)
+	if pos := r1.Pos(); pos != 2 {
+		t.Errorf("got position %d, want 2", pos)
+	}
+
+	// r2 has not consumed anything, so r1 can move back to the start.
+	if err := r1.SetPos(0); err != nil {
+		t.Fatal(err)
+	}
+	got, offset, ok = r1.ReadOffset(nil)
+	if !ok {
+		t.Fatal("unexpected end of stream")
+	}
+	if got != 1 || offset != 0 {
+		t.Errorf("got %d at offset %d, want 1 at offset 0", got, offset)
+	}
+
+	r1.SkipToLatest()
+	r2.SkipToLatest()
+	if err := r1.SetPos(0); !errors.Is(err, ErrOffsetRange) {
+		t.Errorf("got error %v, want %v", err, ErrOffsetRange)
+	}
+	if err := r1.SetPos(4); !errors.Is(err, ErrOffsetRange) {
+		t.Errorf("got error %v, want %v", err, ErrOffsetRange)
+	}
+}
+
+func TestFromEarliest(t *testing.T) {
+	w := New[int](Retain(2))
+	w.WriteBatch([]int{1, 2, 3})
+
+	r := w.Reader(FromEarliest())
+	w.Write(4)
+	w.Close()
+
+	got, err := r.Drain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []int{2, 3, 4}) {
+		t.Errorf("got %v, want [2, 3, 4]", got)
+	}
+
+	// Retained items do not count against capacity.
+	w = New[int](Retain(2), Capacity(2, Block))
+	if err = w.WriteBatch([]int{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReaderAt(t *testing.T) {
+	w := New[int](Retain(3))
+	w.WriteBatch([]int{1, 2, 3, 4, 5})
+
+	r, err := w.ReaderAt(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	got, err := r.Drain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []int{4, 5}) {
+		t.Errorf("got %v, want [4, 5]", got)
+	}
+
+	if _, err = w.ReaderAt(1); !errors.Is(err, ErrOffsetRange) {
+		t.Errorf("got error %v, want %v", err, ErrOffsetRange)
+	}
+}
+
+func TestMaxLag(t *testing.T) {
+	w := New[int]()
+	r := w.Reader(MaxLag(2))
+
+	for i := 1; i <= 5; i++ {
+		w.Write(i)
+	}
+
+	got, ok := r.NBRead()
+	if !ok {
+		t.Fatal("unexpected failure from NBRead")
+	}
+	if got != 4 {
+		t.Errorf("got %d, want 4", got)
+	}
+	if n := r.Skipped(); n != 3 {
+		t.Errorf("got %d skipped, want 3", n)
+	}
+
+	got, ok = r.NBRead()
+	if !ok {
+		t.Fatal("unexpected failure from NBRead")
+	}
+	if got != 5 {
+		t.Errorf("got %d, want 5", got)
+	}
+	if n := r.Skipped(); n != 0 {
+		t.Errorf("got %d skipped, want 0", n)
+	}
+}
+
+func TestConflate(t *testing.T) {
+	w := New[int]()
+	r := w.Reader(Conflate())
+
+	w.WriteBatch([]int{1, 2, 3})
+
+	got, ok := r.NBRead()
+	if !ok {
+		t.Fatal("unexpected failure from NBRead")
+	}
+	if got != 3 {
+		t.Errorf("got %d, want 3", got)
+	}
+	if _, ok = r.NBRead(); ok {
+		t.Error("unexpected success from NBRead")
+	}
+
+	w.Write(4)
+	w.Write(5)
+	got, ok = r.NBRead()
+	if !ok {
+		t.Fatal("unexpected failure from NBRead")
+	}
+	if got != 5 {
+		t.Errorf("got %d, want 5", got)
+	}
+}
+
+func TestWhere(t *testing.T) {
+	even := func(n int) bool { return n%2 == 0 }
+
+	w := New[int](Retain(4))
+	w.WriteBatch([]int{1, 2, 3})
+
+	all := w.Reader()
+	r := w.Reader(Where(even), FromEarliest())
+	w.WriteBatch([]int{4, 5, 6, 7})
+
+	if n := r.Pending(); n != 3 {
+		t.Errorf("got %d pending, want 3", n)
+	}
+	all.Skip(4)
+	w.Close()
+
+	got, err := r.Drain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []int{2, 4, 6}) {
+		t.Errorf("got %v, want [2, 4, 6]", got)
+	}
+
+	// Rejected items are not retained for the reader.
+	w = New[int]()
+	r = w.Reader(Where(even))
+	w.WriteBatch([]int{1, 3, 5})
+	if n := w.Len(); n != 0 {
+		t.Errorf("got length %d, want 0", n)
+	}
+	w.WriteBatch([]int{6, 7})
+	if n := w.Len(); n != 2 {
+		t.Errorf("got length %d, want 2", n)
+	}
+	if got, ok := r.NBRead(); !ok || got != 6 {
+		t.Errorf("got %d, %v; want 6, true", got, ok)
+	}
+	if n := w.Len(); n != 0 {
+		t.Errorf("got length %d, want 0", n)
+	}
+
+	// Nor do they count toward the reader's lag.
+	w = New[int]()
+	r = w.Reader(Where(even), MaxLag(2))
+	for i := 1; i <= 10; i++ {
+		w.Write(i)
+	}
+	if got, ok := r.NBRead(); !ok || got != 8 {
+		t.Errorf("got %d, %v; want 8, true", got, ok)
+	}
+	if n := r.Skipped(); n != 3 {
+		t.Errorf("got %d skipped, want 3", n)
+	}
+
+	if _, err := w.ReaderAt(r.Pos(), Where(func(string) bool { return true })); !errors.Is(err, ErrOptionType) {
+		t.Errorf("got error %v, want %v", err, ErrOptionType)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("no panic for mismatched predicate type")
+		}
+	}()
+	w.Reader(Where(func(string) bool { return true }))
+}
+
+func TestNewChecked(t *testing.T) {
+	cases := []Option{
+		RetainBytes(10, func(s string) int { return len(s) }),
+		Compact(func(s string) string { return s }),
+		DedupConsecutive(func(a, b string) bool { return a == b }),
+		DeadLetter(func(string, DropReason) {}),
+		OnDrop(func(string, ReaderInfo, DropReason) {}),
+		Spill[string](10, JSONCodec[string]{}),
+		Instrument[string](NopHooks[string]{}),
+	}
+	for i, opt := range cases {
+		if _, err := NewChecked[int](opt); !errors.Is(err, ErrOptionType) {
+			t.Errorf("case %d: got error %v, want %v", i+1, err, ErrOptionType)
+		}
+		if _, err := NewChecked[string](opt); err != nil {
+			t.Errorf("case %d: %s", i+1, err)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("no panic from New")
+		}
+	}()
+	New[int](cases[0])
+}
+
+func TestReaderCounts(t *testing.T) {
+	w := New[int](Capacity(2, EvictSlowest))
+	r1 := w.Reader(Where(func(int) bool { return true }), ReceiveErrors())
+	r2 := w.Reader(MaxLag(1))
+	w.Reader(MaxLag(1)).Dispose()
+
+	count := func() [3]int {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return [3]int{w.numFiltered, w.numErrors, w.numMaxLag}
+	}
+	if got, want := count(), [3]int{1, 1, 1}; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Evict r1, which falls further behind than r2.
+	w.WriteBatch([]int{1, 2, 3})
+	if got, want := count(), [3]int{0, 0, 1}; got != want {
+		t.Errorf("after eviction, got %v, want %v", got, want)
+	}
+	if !r1.Closed() {
+		t.Fatal("r1 was not evicted")
+	}
+
+	r2.Dispose()
+	if got, want := count(), [3]int{0, 0, 0}; got != want {
+		t.Errorf("after disposal, got %v, want %v", got, want)
+	}
+}
+
+func TestSticky(t *testing.T) {
+	w := New[int](Sticky())
+
+	r1 := w.Reader()
+	if _, ok := r1.NBRead(); ok {
+		t.Error("unexpected success from NBRead")
+	}
+
+	w.WriteBatch([]int{1, 2})
+
+	r2 := w.Reader()
+	w.Write(3)
+	w.Close()
+
+	got, err := r1.Drain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("reader 1: got %v, want [1, 2, 3]", got)
+	}
+	got, err = r2.Drain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []int{2, 3}) {
+		t.Errorf("reader 2: got %v, want [2, 3]", got)
+	}
+}
+
+func TestReplayLast(t *testing.T) {
+	w := New[int](ReplayLast(3))
+	w.WriteBatch([]int{1, 2, 3, 4, 5})
+
+	r := w.Reader()
+	w.Write(6)
+	w.Close()
+
+	got, err := r.Drain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []int{3, 4, 5, 6}) {
+		t.Errorf("got %v, want [3, 4, 5, 6]", got)
+	}
+}
+
+func TestRetainFor(t *testing.T) {
+	clock := newTestClock()
+	w := New[int](RetainFor(50*time.Millisecond), UseClock(clock))
+	w.WriteBatch([]int{1, 2})
+	clock.advance(100 * time.Millisecond)
+	w.Write(3)
+
+	r := w.Reader(FromEarliest())
+	w.Close()
+
+	got, err := r.Drain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []int{3}) {
+		t.Errorf("got %v, want [3]", got)
+	}
+}
+
+func TestRetainBytes(t *testing.T) {
+	w := New[string](RetainBytes(8, func(s string) int { return len(s) }))
+	w.WriteBatch([]string{"abcd", "efg", "hi", "j"})
+
+	r := w.Reader(FromEarliest())
+	w.Close()
+
+	got, err := r.Drain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{"efg", "hi", "j"}) {
+		t.Errorf("got %v, want [efg hi j]", got)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	type kv struct {
+		k string
+		v int
+	}
+
+	w := New[kv](Compact(func(x kv) string { return x.k }))
+	r1 := w.Reader()
+
+	w.WriteBatch([]kv{{"a", 1}, {"b", 1}, {"a", 2}, {"c", 1}, {"b", 2}})
+
+	// Nothing is compacted while r1 has unread items.
+	r2 := w.Reader(FromEarliest())
+	if n := r2.SkipToLatest(); n != 5 {
+		t.Errorf("skipped %d, want 5", n)
+	}
+	r2.Dispose()
+
+	r1.SkipToLatest()
+	w.Write(kv{"c", 2})
+
+	r3 := w.Reader(FromEarliest())
+	w.Close()
+
+	got, err := r3.Drain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []kv{{"a", 2}, {"b", 2}, {"c", 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestDedupConsecutive(t *testing.T) {
+	w := New[int](DedupConsecutive(func(a, b int) bool { return a == b }))
+	r := w.Reader()
+
+	w.WriteBatch([]int{1, 1, 2, 2, 2, 1})
+	w.Write(1)
+	if !w.TryWrite(1) {
+		t.Error("unexpected failure from TryWrite")
+	}
+	w.Write(3)
+	w.Close()
+
+	got, err := r.Drain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []int{1, 2, 1, 3}) {
+		t.Errorf("got %v, want [1, 2, 1, 3]", got)
+	}
+}
+
+func TestPrioritized(t *testing.T) {
+	w := New[string](Prioritized())
+	r := w.Reader()
+
+	w.Write("a")
+	w.WriteWithPriority("b", 1)
+	w.Write("c")
+	w.WriteWithPriority("d", 2)
+	w.WriteWithPriority("e", 1)
+
+	got := r.ReadN(nil, 3)
+	if !reflect.DeepEqual(got, []string{"d", "b", "e"}) {
+		t.Errorf("got %v, want [d b e]", got)
+	}
+
+	w.WriteWithPriority("f", 1)
+	w.Close()
+
+	got, err := r.Drain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{"f", "a", "c"}) {
+		t.Errorf("got %v, want [f a c]", got)
+	}
+}
+
+// tokenLimiter is a Limiter that permits a fixed number of events.
+type tokenLimiter struct {
+	mu     sync.Mutex
+	tokens int
+}
+
+func (l *tokenLimiter) Allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.tokens > 0 {
+		l.tokens--
+		return true
+	}
+	return false
+}
+
+func (l *tokenLimiter) Wait(ctx context.Context) error {
+	for !l.Allow() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Millisecond):
+		}
+	}
+	return nil
+}
+
+func TestThrottle(t *testing.T) {
+	w := New[int]()
+	lim := &tokenLimiter{tokens: 2}
+	r := w.Reader(Throttle(lim))
+
+	w.WriteBatch([]int{1, 2, 3})
+
+	got := r.ReadN(nil, 0)
+	if !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("got %v, want [1]", got)
+	}
+	got = r.ReadN(nil, 0)
+	if !reflect.DeepEqual(got, []int{2}) {
+		t.Errorf("got %v, want [2]", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, ok := r.Read(ctx); ok {
+		t.Error("unexpected success from throttled Read")
+	}
+
+	lim.mu.Lock()
+	lim.tokens = 1
+	lim.mu.Unlock()
+
+	got1, ok := r.Read(nil)
+	if !ok {
+		t.Fatal("unexpected end of stream")
+	}
+	if got1 != 3 {
+		t.Errorf("got %d, want 3", got1)
+	}
+}
+
+func TestThrottleDrop(t *testing.T) {
+	w := New[int]()
+	lim := &tokenLimiter{tokens: 1}
+	r := w.Reader(ThrottleDrop(lim))
+
+	w.WriteBatch([]int{1, 2, 3})
+
+	got, ok := r.NBRead()
+	if !ok {
+		t.Fatal("unexpected failure from NBRead")
+	}
+	if got != 1 {
+		t.Errorf("got %d, want 1", got)
+	}
+	if _, ok = r.NBRead(); ok {
+		t.Error("unexpected success from NBRead")
+	}
+
+	lim.mu.Lock()
+	lim.tokens = 1
+	lim.mu.Unlock()
+
+	w.Write(4)
+	got, ok = r.NBRead()
+	if !ok {
+		t.Fatal("unexpected failure from NBRead")
+	}
+	if got != 4 {
+		t.Errorf("got %d, want 4", got)
+	}
+	if n := r.Skipped(); n != 2 {
+		t.Errorf("got %d skipped, want 2", n)
+	}
+}
+
+func TestDeadLetter(t *testing.T) {
+	type dead struct {
+		val    int
+		reason DropReason
+	}
+
+	cases := []struct {
+		overflow Overflow
+		want     []dead
+	}{
+		{overflow: DropOldest, want: []dead{{1, DroppedOverflow}}},
+		{overflow: DropNewest, want: []dead{{3, DroppedOverflow}}},
+	}
+	for _, tc := range cases {
+		var got []dead
+		w := New[int](Capacity(2, tc.overflow), DeadLetter(func(val int, reason DropReason) {
+			got = append(got, dead{val, reason})
+		}))
+		r := w.Reader()
+		w.WriteBatch([]int{1, 2, 3})
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("overflow %d: got %v, want %v", tc.overflow, got, tc.want)
+		}
+		r.Dispose()
+	}
+}
+
+func TestReaderInfo(t *testing.T) {
+	w := New[int]()
+	if n := w.NumReaders(); n != 0 {
+		t.Errorf("got %d readers, want 0", n)
+	}
+
+	r1 := w.Reader()
+	w.WriteBatch([]int{1, 2, 3})
+	r2 := w.Reader()
+	w.Write(4)
+	r1.Read(nil)
+
+	if n := w.NumReaders(); n != 2 {
+		t.Errorf("got %d readers, want 2", n)
+	}
+
+	got := w.ReaderInfo()
+	sort.Slice(got, func(i, j int) bool { return got[i].Pos < got[j].Pos })
+	want := []ReaderInfo{{Pos: 1, Lag: 3}, {Pos: 3, Lag: 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	r1.Dispose()
+	r2.Dispose()
+	if n := w.NumReaders(); n != 0 {
+		t.Errorf("got %d readers after Dispose, want 0", n)
+	}
+}
+
+func TestLenPending(t *testing.T) {
+	w := New[int](Retain(1))
+	w.Write(1)
+	if n := w.Len(); n != 1 {
+		t.Errorf("got Len %d, want 1", n)
+	}
+
+	r := w.Reader()
+	w.WriteBatch([]int{2, 3})
+	if n := w.Len(); n != 2 {
+		t.Errorf("got Len %d, want 2", n)
+	}
+	if n := r.Pending(); n != 2 {
+		t.Errorf("got Pending %d, want 2", n)
+	}
+
+	r.Read(nil)
+	if n := r.Pending(); n != 1 {
+		t.Errorf("got Pending %d, want 1", n)
+	}
+	r.Read(nil)
+	if n := r.Pending(); n != 0 {
+		t.Errorf("got Pending %d, want 0", n)
+	}
+	if n := w.Len(); n != 1 {
+		t.Errorf("got Len %d, want 1", n)
+	}
+}
+
+func TestClosed(t *testing.T) {
+	w := New[int]()
+	r := w.Reader()
+	if w.Closed() {
+		t.Error("new writer is closed")
+	}
+	if r.Closed() {
+		t.Error("new reader is closed")
+	}
+
+	w.Close()
+	if !w.Closed() {
+		t.Error("writer is not closed after Close")
+	}
+	if r.Closed() {
+		t.Error("reader is closed before Dispose")
+	}
+
+	r.Dispose()
+	if !r.Closed() {
+		t.Error("reader is not closed after Dispose")
+	}
+}
+
+func TestDone(t *testing.T) {
+	w := New[int]()
+	r := w.Reader()
+	done := r.Done()
+
+	w.WriteBatch([]int{1, 2})
+	w.Close()
+
+	var got []int
+	for {
+		select {
+		case <-done:
+			if !reflect.DeepEqual(got, []int{1, 2}) {
+				t.Errorf("got %v, want [1 2]", got)
+			}
+			return
+
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+
+		default:
+			val, ok := r.NBRead()
+			if !ok {
+				t.Fatal("NBRead failed before Done")
+			}
+			got = append(got, val)
+		}
+	}
+}
+
+func TestDoneDispose(t *testing.T) {
+	w := New[int]()
+	r := w.Reader()
+	w.Write(1)
+	r.Dispose()
+
+	select {
+	case <-r.Done():
+	case <-time.After(time.Second):
+		t.Error("timed out")
+	}
+}
+
+func TestWriterDone(t *testing.T) {
+	w := New[int]()
+	done := w.Done()
+
+	r1, r2 := w.Reader(), w.Reader()
+
+	isClosed := func(ch <-chan struct{}) bool {
+		select {
+		case <-ch:
+			return true
+		default:
+			return false
+		}
+	}
+
+	r1.Dispose()
+	if isClosed(done) {
+		t.Error("Done closed with a reader remaining")
+	}
+	r2.Dispose()
+	if !isClosed(done) {
+		t.Error("Done not closed after last reader disposed")
+	}
+
+	r3 := w.Reader()
+	done = w.Done()
+	if isClosed(done) {
+		t.Error("new Done closed with a reader present")
+	}
+	r3.Dispose()
+	if !isClosed(done) {
+		t.Error("new Done not closed after last reader disposed")
+	}
+}
+
+func TestAutoClose(t *testing.T) {
+	w := New[int](AutoClose(), Retain(10))
+	w.Write(1)
+	if w.Closed() {
+		t.Fatal("closed before any reader")
+	}
+
+	r1, r2 := w.Reader(), w.Reader()
+	w.Write(2)
+	r1.Dispose()
+	if w.Closed() {
+		t.Fatal("closed with a reader remaining")
+	}
+	r2.Dispose()
+	if !w.Closed() {
+		t.Fatal("not closed after last reader disposed")
+	}
+	if n := w.Len(); n != 0 {
+		t.Errorf("got Len %d after auto-close, want 0", n)
+	}
+	if err := w.Write(3); !errors.Is(err, ErrClosed) {
+		t.Errorf("got error %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestFlush(t *testing.T) {
+	w := New[int]()
+	r1, r2 := w.Reader(), w.Reader()
+	w.WriteBatch([]int{1, 2, 3})
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			r1.Read(nil)
+		}
+	}()
+	go r2.Dispose()
+
+	if err := w.Flush(nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := r1.Pending(); n != 0 {
+		t.Errorf("got %d pending after Flush, want 0", n)
+	}
+
+	w.Write(4)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := w.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestWaitForReaders(t *testing.T) {
+	w := New[int]()
+
+	readers := make(chan *R[int], 2)
+	go func() {
+		for i := 0; i < 2; i++ {
+			readers <- w.Reader()
+		}
+	}()
+
+	if err := w.WaitForReaders(nil, 2); err != nil {
+		t.Fatal(err)
+	}
+	w.Write(1)
+	w.Close()
+	for i := 0; i < 2; i++ {
+		if got, ok := (<-readers).Read(nil); !ok || got != 1 {
+			t.Errorf("got %d, %v; want 1, true", got, ok)
+		}
+	}
+
+	if err := w.WaitForReaders(nil, 3); !errors.Is(err, ErrClosed) {
+		t.Errorf("got error %v, want %v", err, ErrClosed)
+	}
+
+	w = New[int]()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := w.WaitForReaders(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestCloseAndWait(t *testing.T) {
+	w := New[int]()
+	r := w.Reader()
+	w.WriteBatch([]int{1, 2, 3})
+
+	var (
+		got  []int
+		done = make(chan struct{})
+	)
+	go func() {
+		got, _ = r.Drain(nil)
+		close(done)
+	}()
+
+	if err := w.CloseAndWait(nil); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	if !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("got %v, want [1 2 3]", got)
+	}
+}
+
+func TestDoubleDispose(t *testing.T) {
+	w := New[int]()
+	g := w.Group()
+	r1, r2 := g.Reader(), g.Reader()
+
+	r1.Dispose()
+	r1.Dispose()
+	g.Dispose()
+	g.Dispose()
+
+	// The group is still alive on r2's behalf.
+	w.Write(1)
+	if got, ok := r2.NBRead(); !ok || got != 1 {
+		t.Errorf("got %d, %v; want 1, true", got, ok)
+	}
+
+	done := make(chan bool)
+	go func() {
+		_, ok := r2.Read(nil)
+		done <- ok
+	}()
+	r2.Dispose()
+	if ok := <-done; ok {
+		t.Error("Read succeeded after Dispose")
+	}
+
+	w.Close()
+	w.Close()
+}
+
+func TestUseAfterDispose(t *testing.T) {
+	w := New[int](Retain(10))
+	r := w.Reader()
+	w.WriteBatch([]int{1, 2, 3})
+	r.Dispose()
+
+	if _, ok := r.Read(nil); ok {
+		t.Error("Read succeeded after Dispose")
+	}
+	if _, ok := r.NBRead(); ok {
+		t.Error("NBRead succeeded after Dispose")
+	}
+	if n := r.Skip(1); n != 0 {
+		t.Errorf("Skip skipped %d after Dispose, want 0", n)
+	}
+	if err := r.SetPos(0); !errors.Is(err, ErrDisposed) {
+		t.Errorf("got SetPos error %v, want %v", err, ErrDisposed)
+	}
+	if err := r.Err(); !errors.Is(err, ErrDisposed) {
+		t.Errorf("got Err %v, want %v", err, ErrDisposed)
+	}
+
+	g := w.Group()
+	g.Dispose()
+	defer func() {
+		if recover() == nil {
+			t.Error("Group.Reader did not panic after Dispose")
+		}
+	}()
+	g.Reader()
+}
+
+func TestReaderChurn(t *testing.T) {
+	w := New[int]()
+	keep := w.Reader()
+	defer keep.Dispose()
+
+	for i := 0; i < 1000; i++ {
+		r := w.Reader()
+		w.Write(i)
+		r.Read(nil)
+		r.Dispose()
+	}
+
+	if n := w.NumReaders(); n != 1 {
+		t.Errorf("got %d readers, want 1", n)
+	}
+	keep.SkipToLatest()
+	if n := w.Len(); n != 0 {
+		t.Errorf("got Len %d, want 0", n)
+	}
+}
+
+func BenchmarkWrite(b *testing.B) {
+	w := New[int]()
+	r := w.Reader()
+	defer r.Dispose()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w.Write(i)
+		r.NBRead()
+	}
+}
+
+func TestMinReaderPos(t *testing.T) {
+	w := New[int]()
+	rs := make([]*R[int], 10)
+	for i := range rs {
+		rs[i] = w.Reader()
+	}
+	for i := 0; i < 100; i++ {
+		w.Write(i)
+	}
+
+	for i := 0; i < 200; i++ {
+		r := rs[(i*7)%len(rs)]
+		r.NBRead()
+		if i%50 == 49 {
+			r.Dispose()
+			rs[(i*7)%len(rs)] = w.Reader(FromEarliest())
+		}
+
+		w.mu.Lock()
+		want := w.end()
+		for _, r := range rs {
+			if r.pos < want {
+				want = r.pos
+			}
+		}
+		got := w.minReaderPos()
+		w.mu.Unlock()
+		if got != want {
+			t.Fatalf("step %d: got min position %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestNBReadIdle(t *testing.T) {
+	w := New[int](Retain(10))
+	r := w.Reader()
+	w.Write(1)
+
+	if got, ok := r.NBRead(); !ok || got != 1 {
+		t.Fatalf("got %d, %v; want 1, true", got, ok)
+	}
+	if _, ok := r.NBRead(); ok {
+		t.Fatal("unexpected success from NBRead on idle reader")
+	}
+
+	// Repositioning the reader makes items available without a write.
+	if err := r.SetPos(0); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := r.NBRead(); !ok || got != 1 {
+		t.Errorf("got %d, %v after SetPos; want 1, true", got, ok)
+	}
+
+	w.Write(2)
+	if got, ok := r.NBRead(); !ok || got != 2 {
+		t.Errorf("got %d, %v; want 2, true", got, ok)
+	}
+}
+
+func BenchmarkNBReadIdle(b *testing.B) {
+	w := New[int]()
+	r := w.Reader()
+	defer r.Dispose()
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			r.NBRead()
+		}
+	})
+}
+
+func TestOnDrop(t *testing.T) {
+	type drop struct {
+		val    int
+		reader string
+		reason DropReason
+	}
+	var got []drop
+	w := New[int](Capacity(3, DropOldest), OnDrop(func(val int, info ReaderInfo, reason DropReason) {
+		got = append(got, drop{val, info.Name, reason})
+	}))
+	a := w.Reader(Name("a"))
+	b := w.Reader(Name("b"), MaxLag(2))
+	defer a.Dispose()
+	defer b.Dispose()
+
+	w.WriteBatch([]int{1, 2, 3, 4})
+
+	want := []drop{
+		{1, "b", DroppedSkipped},
+		{1, "a", DroppedOverflow},
+		{2, "b", DroppedSkipped},
 	}
-	if gotInt != 2 {
-		t.Errorf("got %d, want 2", gotInt)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
 	}
 }
@@ -0,0 +1,124 @@
+package multichantest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bobg/multichan"
+)
+
+// FakeClock is a multichan.Clock whose time changes only when told to,
+// for testing time-based behavior
+// (such as multichan.TTL and multichan.AckTimeout)
+// without waiting.
+// Pass it to multichan.UseClock.
+// Create one with NewFakeClock.
+type FakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer // active timers, in no particular order
+}
+
+var _ multichan.Clock = (*FakeClock)(nil)
+
+// NewFakeClock produces a new FakeClock
+// whose time starts at now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now implements multichan.Clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// AfterFunc implements multichan.Clock.
+// The function is called by Advance,
+// once the clock reaches the timer's expiration time,
+// even if d is not positive.
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) multichan.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{c: c, f: f}
+	t.arm(d)
+	return t
+}
+
+// Advance moves the clock forward by d,
+// calling the functions of the timers expiring along the way
+// in the order of their expiration times,
+// with the clock set to each time in turn.
+// The functions are called synchronously,
+// so when Advance returns,
+// their effects are complete.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	for {
+		var next *fakeTimer
+		for _, t := range c.timers {
+			if !t.at.After(end) && (next == nil || t.at.Before(next.at)) {
+				next = t
+			}
+		}
+		if next == nil {
+			break
+		}
+		if next.at.After(c.now) {
+			c.now = next.at
+		}
+		next.disarm()
+
+		// The function may use the clock.
+		c.mu.Unlock()
+		next.f()
+		c.mu.Lock()
+	}
+	c.now = end
+	c.mu.Unlock()
+}
+
+type fakeTimer struct {
+	c  *FakeClock
+	f  func()
+	at time.Time
+}
+
+// Stop implements multichan.Timer.
+func (t *fakeTimer) Stop() bool {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+	return t.disarm()
+}
+
+// Reset implements multichan.Timer.
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+	active := t.disarm()
+	t.arm(d)
+	return active
+}
+
+// arm schedules t to fire after d.
+// Callers must hold t.c.mu.
+func (t *fakeTimer) arm(d time.Duration) {
+	t.at = t.c.now.Add(d)
+	t.c.timers = append(t.c.timers, t)
+}
+
+// disarm unschedules t,
+// reporting whether it was scheduled.
+// Callers must hold t.c.mu.
+func (t *fakeTimer) disarm() bool {
+	for i, other := range t.c.timers {
+		if other == t {
+			t.c.timers = append(t.c.timers[:i], t.c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package multichantest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bobg/multichan"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+
+	var fired []time.Time
+	record := func() { fired = append(fired, c.Now()) }
+
+	c.AfterFunc(2*time.Second, record)
+	t1 := c.AfterFunc(time.Second, record)
+	t3 := c.AfterFunc(3*time.Second, record)
+	if !t3.Stop() {
+		t.Error("Stop of an active timer returned false")
+	}
+
+	c.Advance(500 * time.Millisecond)
+	if len(fired) != 0 {
+		t.Fatalf("got %d timers fired, want 0", len(fired))
+	}
+	c.Advance(5 * time.Second)
+	if len(fired) != 2 || !fired[0].Equal(start.Add(time.Second)) || !fired[1].Equal(start.Add(2*time.Second)) {
+		t.Errorf("got timers fired at %v, want at 1s and 2s", fired)
+	}
+	if got, want := c.Now(), start.Add(5500*time.Millisecond); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if t1.Reset(time.Second) {
+		t.Error("Reset of a fired timer returned true")
+	}
+	c.Advance(time.Second)
+	if len(fired) != 3 {
+		t.Errorf("got %d timers fired, want 3", len(fired))
+	}
+}
+
+func TestFakeClockTTL(t *testing.T) {
+	c := NewFakeClock(time.Now())
+	w := multichan.New[int](multichan.UseClock(c), multichan.TTL(time.Minute))
+	r := w.Reader()
+
+	w.Write(1)
+	c.Advance(30 * time.Second)
+	w.Write(2)
+	c.Advance(45 * time.Second)
+
+	// Item 1 has expired, item 2 has not.
+	ExpectNext(t, r, 2, time.Second)
+	ExpectNothing(t, r, 10*time.Millisecond)
+}
+
+func TestFakeClockAckTimeout(t *testing.T) {
+	c := NewFakeClock(time.Now())
+	w := multichan.New[int](multichan.UseClock(c))
+	r := w.Reader(multichan.AckTimeout(time.Minute))
+
+	w.Write(1)
+	ctx := context.Background()
+	if d, ok := r.ReadAck(ctx); !ok || d.Val != 1 {
+		t.Fatalf("got %v, %v; want 1, true", d.Val, ok)
+	}
+	ExpectNothing(t, r, 10*time.Millisecond)
+
+	// Not acknowledged in time, so redelivered.
+	c.Advance(time.Minute)
+	if d, ok := r.ReadAck(ctx); !ok || d.Val != 1 {
+		t.Fatalf("got %v, %v; want 1, true", d.Val, ok)
+	} else {
+		d.Ack()
+	}
+}
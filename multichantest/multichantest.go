@@ -0,0 +1,164 @@
+// Package multichantest provides helpers for testing code built on multichans.
+package multichantest
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bobg/multichan"
+)
+
+// Recorder collects the items of a multichan for later assertions.
+// Create one with Record.
+type Recorder[T any] struct {
+	r    *multichan.R[T]
+	mu   sync.Mutex
+	vals []T
+	err  error
+	done chan struct{}
+}
+
+// Record adds a reader to w,
+// created with the given options,
+// and starts a goroutine collecting every item it reads
+// until the multichan is closed and the last item has been consumed
+// (or the reader is evicted, or the recording is stopped with Stop),
+// then disposes of the reader.
+func Record[T any](w *multichan.W[T], opts ...multichan.ReaderOption) *Recorder[T] {
+	var (
+		r   = w.Reader(opts...)
+		rec = &Recorder[T]{r: r, done: make(chan struct{})}
+	)
+	go func() {
+		defer close(rec.done)
+		defer r.Dispose()
+		for {
+			val, ok := r.Read(nil)
+			if !ok {
+				break
+			}
+			rec.mu.Lock()
+			rec.vals = append(rec.vals, val)
+			rec.mu.Unlock()
+		}
+		rec.mu.Lock()
+		rec.err = r.Err()
+		rec.mu.Unlock()
+	}()
+	return rec
+}
+
+// Stop ends the recording,
+// disposing of its reader
+// so that it no longer holds items in the multichan
+// or counts among its readers.
+// The error reported by Wait is then multichan.ErrDisposed,
+// unless the stream ended first.
+func (rec *Recorder[T]) Stop() {
+	rec.r.Dispose()
+	<-rec.done
+}
+
+// Items returns the items collected so far.
+func (rec *Recorder[T]) Items() []T {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]T(nil), rec.vals...)
+}
+
+// Wait waits for the end of the stream,
+// then returns all the items collected
+// and the reader's error (see multichan.R.Err).
+// If the stream has not ended within the timeout,
+// Wait fails the test with t.Fatal.
+func (rec *Recorder[T]) Wait(t testing.TB, timeout time.Duration) ([]T, error) {
+	t.Helper()
+
+	select {
+	case <-rec.done:
+	case <-time.After(timeout):
+		t.Fatalf("stream did not end within %v (got %d items)", timeout, len(rec.Items()))
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.vals, rec.err
+}
+
+// Next reads the next item from r and returns it.
+// If no item arrives within the timeout,
+// or if the stream ends instead,
+// Next fails the test with t.Fatal.
+func Next[T any](t testing.TB, r *multichan.R[T], timeout time.Duration) T {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	val, ok := r.Read(ctx)
+	if !ok {
+		if ctx.Err() != nil {
+			t.Fatalf("no item within %v", timeout)
+		}
+		t.Fatalf("got end of stream (error %v), want an item", r.Err())
+	}
+	return val
+}
+
+// ExpectNext reads the next item from r
+// and reports a test error if it is not equal to want
+// (as determined by reflect.DeepEqual).
+// If no item arrives within the timeout,
+// or if the stream ends instead,
+// ExpectNext fails the test with t.Fatal.
+func ExpectNext[T any](t testing.TB, r *multichan.R[T], want T, timeout time.Duration) {
+	t.Helper()
+
+	if got := Next(t, r, timeout); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// ExpectClosed reads from r,
+// expecting the end of the stream,
+// and returns the reader's error (see multichan.R.Err)
+// for further assertions.
+// If an item arrives instead,
+// ExpectClosed reports a test error.
+// If nothing happens within the timeout,
+// ExpectClosed fails the test with t.Fatal.
+func ExpectClosed[T any](t testing.TB, r *multichan.R[T], timeout time.Duration) error {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	val, ok := r.Read(ctx)
+	switch {
+	case ok:
+		t.Errorf("got item %v, want end of stream", val)
+	case ctx.Err() != nil:
+		t.Fatalf("stream did not end within %v", timeout)
+	}
+	return r.Err()
+}
+
+// ExpectNothing reports a test error
+// if an item arrives on r
+// (which is consumed),
+// or the stream ends,
+// within the duration d.
+func ExpectNothing[T any](t testing.TB, r *multichan.R[T], d time.Duration) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	if val, ok := r.Read(ctx); ok {
+		t.Errorf("got item %v, want nothing", val)
+	} else if ctx.Err() == nil {
+		t.Errorf("got end of stream (error %v), want nothing", r.Err())
+	}
+}
@@ -0,0 +1,71 @@
+package multichantest
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bobg/multichan"
+)
+
+func TestRecorder(t *testing.T) {
+	w := multichan.New[int]()
+	rec := Record(w)
+
+	errTest := errors.New("test")
+	w.WriteBatch([]int{1, 2, 3})
+	w.CloseWithError(errTest)
+
+	got, err := rec.Wait(t, time.Second)
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if err != errTest {
+		t.Errorf("got %v, want %v", err, errTest)
+	}
+	if items := rec.Items(); !reflect.DeepEqual(items, got) {
+		t.Errorf("got %v, want %v", items, got)
+	}
+	if n := w.NumReaders(); n != 0 {
+		t.Errorf("got %d readers after the end of the stream, want 0", n)
+	}
+}
+
+func TestExpect(t *testing.T) {
+	w := multichan.New[string]()
+	r := w.Reader()
+
+	ExpectNothing(t, r, 10*time.Millisecond)
+	w.Write("a")
+	w.Write("b")
+	ExpectNext(t, r, "a", time.Second)
+	if got := Next(t, r, time.Second); got != "b" {
+		t.Errorf("got %q, want \"b\"", got)
+	}
+	w.Close()
+	if err := ExpectClosed(t, r, time.Second); err != nil {
+		t.Errorf("got %v, want nil", err)
+	}
+}
+
+func TestRecorderStop(t *testing.T) {
+	w := multichan.New[int]()
+	rec := Record(w)
+	w.Write(1)
+	for len(rec.Items()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	rec.Stop()
+	if n := w.NumReaders(); n != 0 {
+		t.Errorf("got %d readers, want 0", n)
+	}
+	got, err := rec.Wait(t, time.Second)
+	if want := []int{1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if !errors.Is(err, multichan.ErrDisposed) {
+		t.Errorf("got %v, want %v", err, multichan.ErrDisposed)
+	}
+}
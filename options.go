@@ -0,0 +1,585 @@
+package multichan
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Option is the type of an option that can be passed to New.
+type Option func(*config)
+
+type config struct {
+	capacity    int
+	overflow    Overflow
+	retain      int
+	retainFor   time.Duration
+	retainBytes int
+	sizer       any // func(T) int, for the multichan's T
+	replay      int
+	key         any // func(T) any, for the multichan's T
+	eq          any // func(a, b T) bool, for the multichan's T
+	ttl         time.Duration
+	prioritized bool
+	deadLetter  any // func(T, DropReason), for the multichan's T
+	autoClose   bool
+	hold        bool
+	expvar      string
+
+	lagHigh, lagLow int
+	onLag           func(info ReaderInfo, behind bool)
+
+	onDrop any // func(T, ReaderInfo, DropReason), for the multichan's T
+	hooks  any // Hooks[T], for the multichan's T
+
+	logger    *slog.Logger
+	logLevels LogLevels
+
+	spillItems int
+	spillBytes int
+	spillSize  any // func(T) int, for the multichan's T
+	spillCodec any // Codec[T], for the multichan's T
+	spillDir   string
+
+	clock Clock
+}
+
+// Overflow is a policy for what Write does when a multichan is at capacity.
+// See Capacity.
+type Overflow int
+
+const (
+	// Block causes Write to wait until the slowest reader consumes an item,
+	// making room in the queue.
+	Block Overflow = iota
+
+	// DropOldest causes Write never to block.
+	// Instead, the oldest item in the queue is discarded to make room for the new one,
+	// and any readers that had not yet consumed it skip past it.
+	DropOldest
+
+	// DropNewest causes Write never to block.
+	// Instead, the item being written is silently discarded
+	// (but see DeadLetter),
+	// preserving the items already in the queue.
+	DropNewest
+
+	// EvictSlowest causes Write never to block.
+	// Instead, the reader furthest behind is disposed of
+	// (along with any others tied with it)
+	// until there is room in the queue.
+	// An evicted reader's subsequent reads fail,
+	// and its Err method returns ErrEvicted.
+	EvictSlowest
+)
+
+// Capacity limits the number of items a multichan retains
+// (i.e., the number written but not yet consumed by every reader)
+// to n.
+// When a Write would exceed that limit,
+// the overflow policy determines what happens.
+// A value of n less than 1 means no limit, which is the default.
+func Capacity(n int, overflow Overflow) Option {
+	return func(c *config) {
+		c.capacity = n
+		c.overflow = overflow
+	}
+}
+
+// Retain causes a multichan to keep the most recent n items
+// even after every reader has consumed them
+// (or when there are no readers),
+// so that readers created with FromEarliest
+// (or repositioned with R.SetPos)
+// can see them.
+// Items kept only for this reason do not count against the limit set with Capacity:
+// they are discarded as needed to make room for new items.
+func Retain(n int) Option {
+	return func(c *config) {
+		c.retain = n
+	}
+}
+
+// RetainFor causes a multichan to keep items written within the last d
+// even after every reader has consumed them
+// (or when there are no readers),
+// so that readers created with FromEarliest
+// (or repositioned with R.SetPos)
+// can see them.
+// Expired items are discarded whenever the multichan is written or read.
+// As with Retain,
+// items kept only for this reason do not count against the limit set with Capacity.
+func RetainFor(d time.Duration) Option {
+	return func(c *config) {
+		c.retainFor = d
+	}
+}
+
+// RetainBytes causes a multichan to keep the most recent items
+// totaling no more than n bytes
+// even after every reader has consumed them
+// (or when there are no readers),
+// so that readers created with FromEarliest
+// (or repositioned with R.SetPos)
+// can see them.
+// The size of each item is computed,
+// when it is written,
+// with the given function.
+// As with Retain,
+// items kept only for this reason do not count against the limit set with Capacity.
+//
+// The type parameter T must match that of the multichan,
+// or New will panic (see NewChecked).
+func RetainBytes[T any](n int, size func(T) int) Option {
+	return func(c *config) {
+		c.retainBytes = n
+		c.sizer = size
+	}
+}
+
+// Compact causes a multichan to retain,
+// for each distinct key,
+// the latest item with that key
+// even after every reader has consumed it
+// (or when there are no readers),
+// so that readers created with FromEarliest
+// (or repositioned with R.SetPos)
+// can see it.
+// Other items are discarded once every reader has consumed them.
+// The key of each item is computed,
+// when it is written,
+// with the given function.
+// This is the pattern of a "compacted topic" for streams of state updates.
+//
+// Compact may be combined with Retain, RetainFor, and RetainBytes,
+// in which case items are retained only as those options allow
+// and, among those, superseded items are discarded.
+// As with Retain,
+// items kept only for this reason do not count against the limit set with Capacity.
+//
+// The type parameter T must match that of the multichan,
+// or New will panic (see NewChecked).
+func Compact[T any, K comparable](key func(T) K) Option {
+	return func(c *config) {
+		c.key = func(val T) any { return key(val) }
+	}
+}
+
+// DedupConsecutive causes a multichan to discard any item written
+// that is equal to the item written just before it,
+// according to the given function.
+// This spares readers from waking up for no-op updates.
+// Writing a duplicate item is not an error.
+//
+// The type parameter T must match that of the multichan,
+// or New will panic (see NewChecked).
+func DedupConsecutive[T any](eq func(a, b T) bool) Option {
+	return func(c *config) {
+		c.eq = eq
+	}
+}
+
+// TTL causes items written to a multichan to expire after d.
+// An expired item is removed from the stream
+// even if some readers have not yet consumed it;
+// those readers skip over it as if it had never been written.
+// Expired items do not count against the limit set with Capacity.
+// The expiration time of an individual item can be set with W.WriteWithTTL.
+func TTL(d time.Duration) Option {
+	return func(c *config) {
+		c.ttl = d
+	}
+}
+
+// Prioritized causes the readers of a multichan
+// to receive the items available to them in priority order
+// (highest first)
+// instead of the order in which they were written.
+// Items of equal priority are received in the order written.
+// The priority of an item is set with W.WriteWithPriority;
+// it is 0 for items written in other ways.
+func Prioritized() Option {
+	return func(c *config) {
+		c.prioritized = true
+	}
+}
+
+// DropReason tells why an item was sent to a dead-letter function
+// (see DeadLetter).
+type DropReason int
+
+const (
+	// DroppedOverflow means the item was discarded by the DropOldest or DropNewest overflow policy
+	// (see Capacity).
+	DroppedOverflow DropReason = iota + 1
+
+	// DroppedExpired means the item expired (see TTL)
+	// before every reader had consumed it.
+	DroppedExpired
+
+	// DroppedRejected means the item was read with R.ReadAck
+	// and Nacked or left unacknowledged too many times
+	// (see MaxAttempts).
+	DroppedRejected
+
+	// DroppedSkipped means a reader skipped the item
+	// because of the MaxLag or ThrottleDrop reader options.
+	// It is reported only to OnDrop functions,
+	// since the item remains available to other readers.
+	DroppedSkipped
+)
+
+func (r DropReason) String() string {
+	switch r {
+	case DroppedOverflow:
+		return "overflow"
+	case DroppedExpired:
+		return "expired"
+	case DroppedRejected:
+		return "rejected"
+	case DroppedSkipped:
+		return "skipped"
+	}
+	return fmt.Sprintf("DropReason(%d)", int(r))
+}
+
+// DeadLetter causes a multichan to call f
+// with each item it discards before every reader has consumed it,
+// and the reason for discarding it,
+// instead of losing the item silently.
+// An item can be sent on to another multichan this way:
+//
+//	dlq := multichan.New[Dead]()
+//	w := multichan.New[T](multichan.DeadLetter(func(val T, reason multichan.DropReason) {
+//	  dlq.TryWrite(Dead{Val: val, Reason: reason})
+//	}))
+//
+// The function is called while the multichan is locked,
+// so it must not block
+// and must not call methods on the multichan or its readers.
+//
+// The type parameter T must match that of the multichan,
+// or New will panic (see NewChecked).
+func DeadLetter[T any](f func(val T, reason DropReason)) Option {
+	return func(c *config) {
+		c.deadLetter = f
+	}
+}
+
+// AutoClose causes a multichan to close itself
+// (as with W.Close)
+// when its last reader goes away
+// (by being disposed of or evicted),
+// discarding any items it retains.
+// A multichan that has never had a reader is not closed this way.
+// This keeps an orphaned writer from accumulating items forever:
+// its writes fail with ErrClosed instead.
+func AutoClose() Option {
+	return func(c *config) {
+		c.autoClose = true
+	}
+}
+
+// HoldUntilReader causes a multichan to retain the items written before it has any readers
+// and to deliver them to its first reader,
+// which starts with the oldest of them
+// (unless it is created with W.ReaderAt).
+// Ordinarily those items are discarded at once,
+// since no reader will see them.
+// Until the first reader arrives,
+// the items count against the limit set with Capacity,
+// so that e.g. with the Block overflow policy
+// writes block once the limit is reached.
+// (With EvictSlowest,
+// which has no reader to evict,
+// the oldest held item is dropped as with DropOldest.)
+func HoldUntilReader() Option {
+	return func(c *config) {
+		c.hold = true
+	}
+}
+
+// OnDrop causes a multichan to call f
+// for each reader that an item is discarded without being delivered to,
+// with the item, a description of the reader, and the reason.
+// Unlike DeadLetter,
+// which reports items lost to every reader,
+// this reports items lost to particular readers,
+// including those skipped by readers using MaxLag or ThrottleDrop.
+// An item discarded by the DropNewest overflow policy
+// is reported once for each reader.
+//
+// As with DeadLetter,
+// f is called while the multichan is locked,
+// so it must not block
+// and must not call methods on the multichan or its readers.
+//
+// The type parameter T must match that of the multichan,
+// or New will panic (see NewChecked).
+func OnDrop[T any](f func(val T, reader ReaderInfo, reason DropReason)) Option {
+	return func(c *config) {
+		c.onDrop = f
+	}
+}
+
+// OnLag causes a multichan to call f
+// when any reader falls behind,
+// with high or more items available to it (see R.Pending),
+// and again when it recovers,
+// with low or fewer.
+// The first argument describes the reader;
+// the second is true when the reader falls behind
+// and false when it recovers.
+// The value of low should be less than that of high.
+// The function f may be nil
+// if the crossings need only be logged (see Logger).
+//
+// As with DeadLetter,
+// f is called while the multichan is locked,
+// so it must not block
+// and must not call methods on the multichan or its readers.
+func OnLag(high, low int, f func(info ReaderInfo, behind bool)) Option {
+	return func(c *config) {
+		c.lagHigh, c.lagLow, c.onLag = high, low, f
+	}
+}
+
+// Expvar causes a multichan to publish its live statistics (see W.Stats)
+// with the expvar package under the given name,
+// so that they appear in /debug/vars.
+// If the name is already in use,
+// NewChecked returns an error wrapping ErrExpvarName
+// (and New panics).
+//
+// A published expvar variable cannot be removed,
+// so once the multichan is closed
+// the variable no longer refers to it
+// (and does not keep it from being garbage-collected)
+// but reports its statistics as of the close.
+// The name remains in use for the life of the process.
+func Expvar(name string) Option {
+	return func(c *config) {
+		c.expvar = name
+	}
+}
+
+// Spill causes a multichan to keep the values of no more than n of its items in memory.
+// When there are more,
+// the values of the oldest are encoded with codec
+// and moved to a temporary file,
+// from which they are read back and decoded as needed.
+// This lets a reader fall far behind the others
+// (see Capacity)
+// without the items it has yet to read exhausting memory.
+// A small amount of bookkeeping for each item remains in memory.
+//
+// The temporary files are created in the directory given with SpillDir,
+// or in the default directory for temporary files.
+// A value that cannot be encoded stays in memory,
+// and so do the values of all newer items
+// if the temporary file cannot be written.
+// Such failures are logged (see Logger).
+// Failure to read back or decode a spilled value causes a panic.
+//
+// The type parameter T must match that of the multichan,
+// or New will panic (see NewChecked).
+func Spill[T any](n int, codec Codec[T]) Option {
+	return func(c *config) {
+		c.spillItems = n
+		c.spillCodec = codec
+	}
+}
+
+// SpillBytes is like Spill,
+// but limits the values kept in memory to a total of n bytes,
+// as measured by the given function.
+// It may be combined with Spill
+// (using the same codec),
+// in which case values spill when either limit is exceeded.
+//
+// The type parameter T must match that of the multichan,
+// or New will panic (see NewChecked).
+func SpillBytes[T any](n int, size func(T) int, codec Codec[T]) Option {
+	return func(c *config) {
+		c.spillBytes = n
+		c.spillSize = size
+		c.spillCodec = codec
+	}
+}
+
+// SpillDir sets the directory for the temporary files of a multichan
+// that uses Spill or SpillBytes.
+func SpillDir(dir string) Option {
+	return func(c *config) {
+		c.spillDir = dir
+	}
+}
+
+// Sticky causes a multichan always to retain the most recently written item
+// and to deliver it to each new reader
+// as the first item it reads
+// (followed by subsequently written items).
+// This gives new readers the "current state" of a stream plus its updates.
+// It is the same as ReplayLast(1).
+func Sticky() Option {
+	return ReplayLast(1)
+}
+
+// ReplayLast causes a multichan always to retain the n most recently written items
+// (as with Retain)
+// and to start each new reader n items back from the end of the stream,
+// so that its first reads deliver those items
+// (or as many of them as have been written).
+// This makes the multichan usable as a cache of recent events for late joiners.
+func ReplayLast(n int) Option {
+	return func(c *config) {
+		c.replay = n
+	}
+}
+
+// ReaderOption is the type of an option that can be passed to W.Reader.
+type ReaderOption func(*readerConfig)
+
+type readerConfig struct {
+	maxLag       int
+	filter       any // func(T) bool, for the multichan's T
+	errors       bool
+	fromEarliest bool
+	limiter      Limiter
+	drop         bool
+	ackTimeout   time.Duration
+	maxAttempts  int
+	name         string
+}
+
+// MaxLag limits how far a reader may fall behind the newest item in the stream.
+// When a Write leaves the reader more than n items behind,
+// the reader skips forward so that it is exactly n items behind,
+// and the items it skipped are no longer retained on its behalf.
+// See R.Skipped.
+// A value of n less than 1 means no limit, which is the default.
+func MaxLag(n int) ReaderOption {
+	return func(c *readerConfig) {
+		c.maxLag = n
+	}
+}
+
+// Conflate puts a reader in latest-value-only mode:
+// each Read returns only the most recent item written since the previous Read,
+// and older unread items are skipped
+// (and not retained on the reader's behalf).
+// It is the same as MaxLag(1).
+func Conflate() ReaderOption {
+	return MaxLag(1)
+}
+
+// Where gives a reader a filter:
+// it reads only the items for which pred returns true.
+// The predicate is called once for each item,
+// when the item is written
+// (or, for items already in the multichan,
+// when the reader is created or repositioned with R.SetPos),
+// so the items it rejects do not count toward the reader's lag
+// (see R.Pending, MaxLag, and OnLag)
+// and are not retained on the reader's behalf.
+// It is called with the multichan locked,
+// so it must not use the multichan.
+// Items of the multichan must have type T;
+// otherwise W.Reader panics
+// (and W.ReaderAt returns an error wrapping ErrOptionType).
+func Where[T any](pred func(T) bool) ReaderOption {
+	return func(c *readerConfig) {
+		c.filter = pred
+	}
+}
+
+// ReceiveErrors causes a reader to receive the errors written with W.WriteError,
+// interleaved with the multichan's items,
+// which it should read with R.ReadResult.
+// Other readers do not see the errors.
+func ReceiveErrors() ReaderOption {
+	return func(c *readerConfig) {
+		c.errors = true
+	}
+}
+
+// Name gives a reader a name,
+// for identifying it in a ReaderInfo
+// (see W.ReaderInfo, OnLag, and OnDrop).
+func Name(name string) ReaderOption {
+	return func(c *readerConfig) {
+		c.name = name
+	}
+}
+
+// FromEarliest causes a new reader to start at the oldest item the multichan retains,
+// rather than at the next item to be written.
+// See Retain.
+func FromEarliest() ReaderOption {
+	return func(c *readerConfig) {
+		c.fromEarliest = true
+	}
+}
+
+// Limiter is the interface of a rate limiter for use with Throttle and ThrottleDrop.
+// It is satisfied by *rate.Limiter from golang.org/x/time/rate.
+type Limiter interface {
+	// Allow reports whether an event may happen now,
+	// consuming a token if so.
+	Allow() bool
+
+	// Wait blocks until an event may happen,
+	// consuming a token,
+	// or until the context is canceled,
+	// in which case it returns an error.
+	Wait(context.Context) error
+}
+
+// Throttle limits the rate at which a reader receives items.
+// Each read waits as necessary for the limiter to permit it,
+// so the reader falls further behind if items arrive faster than that.
+func Throttle(lim Limiter) ReaderOption {
+	return func(c *readerConfig) {
+		c.limiter = lim
+		c.drop = false
+	}
+}
+
+// ThrottleDrop limits the rate at which a reader receives items.
+// Items arriving faster than the limiter permits are skipped
+// (see R.Skipped)
+// rather than delayed.
+func ThrottleDrop(lim Limiter) ReaderOption {
+	return func(c *readerConfig) {
+		c.limiter = lim
+		c.drop = true
+	}
+}
+
+// AckTimeout sets the visibility timeout for items a reader reads with R.ReadAck.
+// An item not acknowledged within d of being read
+// is redelivered,
+// to the same reader or,
+// if the reader belongs to a consumer group (see W.Group),
+// to any member of the group.
+// A value of d less than or equal to 0 means no timeout, which is the default:
+// unacknowledged items are then redelivered only when Nacked.
+func AckTimeout(d time.Duration) ReaderOption {
+	return func(c *readerConfig) {
+		c.ackTimeout = d
+	}
+}
+
+// MaxAttempts limits the number of times an item read with R.ReadAck
+// is delivered.
+// When an item that has been delivered n times is Nacked
+// or its ack timeout expires (see AckTimeout),
+// it is sent to the multichan's dead-letter function (see DeadLetter), if any,
+// instead of being redelivered.
+// A value of n less than 1 means no limit, which is the default.
+func MaxAttempts(n int) ReaderOption {
+	return func(c *readerConfig) {
+		c.maxAttempts = n
+	}
+}
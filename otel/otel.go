@@ -0,0 +1,136 @@
+// Package otel instruments multichans with OpenTelemetry.
+//
+// Create a Hooks object with NewHooks
+// and install it in a multichan with multichan.Instrument:
+//
+//	h, err := otel.NewHooks[T]("events", meterProvider)
+//	if err != nil { ... }
+//	w := multichan.New[T](multichan.Instrument[T](h))
+package otel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/bobg/multichan"
+)
+
+const scope = "github.com/bobg/multichan/otel"
+
+// Hooks is an implementation of multichan.Hooks
+// that records OpenTelemetry metrics,
+// and optionally spans (see WithTracing).
+//
+// The metrics are:
+//
+//   - multichan.writes: a counter of items written
+//   - multichan.reads: a counter of items read, by reader name (see multichan.Name)
+//   - multichan.reader.lag: a histogram of the number of items available to a reader after each read
+//   - multichan.readers: an up-down counter of readers
+//
+// Each has a multichan.name attribute giving the name passed to NewHooks.
+type Hooks[T any] struct {
+	attrs metric.MeasurementOption
+
+	writes  metric.Int64Counter
+	reads   metric.Int64Counter
+	lag     metric.Int64Histogram
+	readers metric.Int64UpDownCounter
+
+	tracer      trace.Tracer
+	spanContext func(T) trace.SpanContext
+}
+
+var _ multichan.Hooks[int] = (*Hooks[int])(nil)
+
+// NewHooks produces a new Hooks object
+// recording metrics for the multichan with the given name
+// using the given meter provider.
+func NewHooks[T any](name string, mp metric.MeterProvider) (*Hooks[T], error) {
+	meter := mp.Meter(scope)
+
+	writes, err := meter.Int64Counter("multichan.writes", metric.WithDescription("Items written to the multichan."))
+	if err != nil {
+		return nil, err
+	}
+	reads, err := meter.Int64Counter("multichan.reads", metric.WithDescription("Items read from the multichan."))
+	if err != nil {
+		return nil, err
+	}
+	lag, err := meter.Int64Histogram("multichan.reader.lag", metric.WithDescription("Items available to a reader after each read."))
+	if err != nil {
+		return nil, err
+	}
+	readers, err := meter.Int64UpDownCounter("multichan.readers", metric.WithDescription("Readers of the multichan."))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Hooks[T]{
+		attrs:   metric.WithAttributes(attribute.String("multichan.name", name)),
+		writes:  writes,
+		reads:   reads,
+		lag:     lag,
+		readers: readers,
+	}, nil
+}
+
+// WithTracing causes h to record a span,
+// using a tracer from the given provider,
+// for each item written to and read from the multichan.
+// The given function extracts the span context attached to an item, if any;
+// the spans for the item are linked to it,
+// so that the spans of a producer and its consumers can be related.
+// It returns h.
+func (h *Hooks[T]) WithTracing(tp trace.TracerProvider, spanContext func(T) trace.SpanContext) *Hooks[T] {
+	h.tracer = tp.Tracer(scope)
+	h.spanContext = spanContext
+	return h
+}
+
+// OnWrite implements multichan.Hooks.
+func (h *Hooks[T]) OnWrite(val T, offset int64) {
+	ctx := context.Background()
+	h.writes.Add(ctx, 1, h.attrs)
+	h.span(ctx, "multichan.write", val, offset, trace.SpanKindProducer)
+}
+
+// OnRead implements multichan.Hooks.
+func (h *Hooks[T]) OnRead(val T, offset int64, reader multichan.ReaderInfo) {
+	ctx := context.Background()
+	h.reads.Add(ctx, 1, h.attrs, metric.WithAttributes(attribute.String("multichan.reader", reader.Name)))
+	h.lag.Record(ctx, int64(reader.Lag), h.attrs)
+	h.span(ctx, "multichan.read", val, offset, trace.SpanKindConsumer)
+}
+
+// OnReaderAdded implements multichan.Hooks.
+func (h *Hooks[T]) OnReaderAdded(multichan.ReaderInfo) {
+	h.readers.Add(context.Background(), 1, h.attrs)
+}
+
+// OnReaderDisposed implements multichan.Hooks.
+func (h *Hooks[T]) OnReaderDisposed(multichan.ReaderInfo) {
+	h.readers.Add(context.Background(), -1, h.attrs)
+}
+
+// OnClose implements multichan.Hooks.
+func (h *Hooks[T]) OnClose(error) {}
+
+// span records a span for val, if tracing is enabled (see WithTracing).
+func (h *Hooks[T]) span(ctx context.Context, name string, val T, offset int64, kind trace.SpanKind) {
+	if h.tracer == nil {
+		return
+	}
+	opts := []trace.SpanStartOption{
+		trace.WithSpanKind(kind),
+		trace.WithAttributes(attribute.Int64("multichan.offset", offset)),
+	}
+	if sc := h.spanContext(val); sc.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: sc}))
+	}
+	_, span := h.tracer.Start(ctx, name, opts...)
+	span.End()
+}
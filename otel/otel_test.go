@@ -0,0 +1,75 @@
+package otel
+
+import (
+	"context"
+	"testing"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/bobg/multichan"
+)
+
+type event struct {
+	sc  trace.SpanContext
+	val int
+}
+
+func TestHooks(t *testing.T) {
+	var (
+		mr = sdkmetric.NewManualReader()
+		mp = sdkmetric.NewMeterProvider(sdkmetric.WithReader(mr))
+		sr = tracetest.NewSpanRecorder()
+		tp = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	)
+
+	h, err := NewHooks[event]("test", mp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.WithTracing(tp, func(e event) trace.SpanContext { return e.sc })
+
+	_, producer := tp.Tracer("test").Start(context.Background(), "producer")
+	sc := producer.SpanContext()
+	producer.End()
+
+	w := multichan.New[event](multichan.Instrument[event](h))
+	r := w.Reader(multichan.Name("r"))
+	w.Write(event{sc: sc, val: 1})
+	w.Write(event{val: 2})
+	r.ReadN(nil, 0)
+	r.Dispose()
+
+	var rm metricdata.ResourceMetrics
+	if err := mr.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	sums := make(map[string]int64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if data, ok := m.Data.(metricdata.Sum[int64]); ok {
+				for _, dp := range data.DataPoints {
+					sums[m.Name] += dp.Value
+				}
+			}
+		}
+	}
+	if sums["multichan.writes"] != 2 || sums["multichan.reads"] != 2 || sums["multichan.readers"] != 0 {
+		t.Errorf("got sums %v, want 2 writes, 2 reads, 0 readers", sums)
+	}
+
+	var linked int
+	for _, span := range sr.Ended() {
+		for _, link := range span.Links() {
+			if link.SpanContext.Equal(sc) {
+				linked++
+			}
+		}
+	}
+	if linked != 2 {
+		t.Errorf("got %d spans linked to the producer, want 2", linked)
+	}
+}
@@ -0,0 +1,214 @@
+package multichan
+
+import (
+	"context"
+	"sync"
+)
+
+// Pipe forwards items from src to dst.
+// It adds a reader to src
+// and starts a goroutine that writes everything read from it to dst,
+// until src is closed and the last item has been consumed,
+// or until the context is canceled.
+// It then closes dst,
+// passing along src's error (see CloseWithError)
+// or the context's error,
+// and disposes of the reader.
+// If dst is closed first,
+// forwarding simply stops.
+// The context argument may be nil.
+func Pipe[T any](ctx context.Context, src, dst *W[T]) {
+	PipeFunc(ctx, src, dst, func(val T) T { return val })
+}
+
+// PipeFunc is like Pipe
+// but transforms each item with f before writing it to dst.
+func PipeFunc[T, U any](ctx context.Context, src *W[T], dst *W[U], f func(T) U) {
+	forward(ctx, src.Reader(), dst, func(val T) (U, bool) { return f(val), true })
+}
+
+// forward starts a goroutine that reads items from r
+// and writes them to dst,
+// after transforming them with f.
+// If f returns false for an item, that item is not written.
+// See Pipe.
+func forward[T, U any](ctx context.Context, r *R[T], dst *W[U], f func(T) (U, bool)) {
+	go func() {
+		defer r.Dispose()
+
+		for {
+			val, ok := r.Read(ctx)
+			if !ok {
+				break
+			}
+			out, ok := f(val)
+			if !ok {
+				continue
+			}
+			if err := dst.WriteContext(ctx, out); err != nil {
+				break
+			}
+		}
+
+		if ctx != nil && ctx.Err() != nil {
+			dst.CloseWithError(ctx.Err())
+		} else {
+			dst.CloseWithError(r.Err())
+		}
+	}()
+}
+
+// Merge produces a new multichan that interleaves the items from all the given ones.
+// It adds a reader to each of ws
+// and starts goroutines forwarding their items to the new multichan,
+// which is closed once all of ws are closed and their last items have been forwarded,
+// or when the context is canceled.
+// When closing,
+// it passes along the context's error,
+// or else the first error from any of ws (see CloseWithError).
+// The context argument may be nil.
+func Merge[T any](ctx context.Context, ws ...*W[T]) *W[T] {
+	var (
+		out = New[T]()
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		err error
+	)
+
+	for _, w := range ws {
+		r := w.Reader()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer r.Dispose()
+
+			for {
+				val, ok := r.Read(ctx)
+				if !ok {
+					break
+				}
+				if out.WriteContext(ctx, val) != nil {
+					return
+				}
+			}
+
+			if rerr := r.Err(); rerr != nil {
+				mu.Lock()
+				if err == nil {
+					err = rerr
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		if ctx != nil && ctx.Err() != nil {
+			out.CloseWithError(ctx.Err())
+		} else {
+			out.CloseWithError(err)
+		}
+	}()
+
+	return out
+}
+
+// Pair is the type of item produced by Zip.
+type Pair[T, U any] struct {
+	First  T
+	Second U
+}
+
+// Zip produces a new multichan that pairs each item from a
+// with the corresponding item from b:
+// the first with the first,
+// the second with the second,
+// and so on.
+// It adds a reader to each of a and b
+// and starts a goroutine to forward the pairs,
+// which closes the new multichan when either a or b is closed and its last item has been consumed,
+// or when the context is canceled.
+// When closing,
+// it passes along the context's error,
+// or else the error from whichever of a and b ended the stream (see CloseWithError).
+// The context argument may be nil.
+func Zip[T, U any](ctx context.Context, a *W[T], b *W[U]) *W[Pair[T, U]] {
+	var (
+		out = New[Pair[T, U]]()
+		ra  = a.Reader()
+		rb  = b.Reader()
+	)
+
+	go func() {
+		defer ra.Dispose()
+		defer rb.Dispose()
+
+		var err error
+		for {
+			first, ok := ra.Read(ctx)
+			if !ok {
+				err = ra.Err()
+				break
+			}
+			second, ok := rb.Read(ctx)
+			if !ok {
+				err = rb.Err()
+				break
+			}
+			if out.WriteContext(ctx, Pair[T, U]{First: first, Second: second}) != nil {
+				break
+			}
+		}
+
+		if ctx != nil && ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		out.CloseWithError(err)
+	}()
+
+	return out
+}
+
+// Map produces a new multichan containing the items of w transformed by f.
+// It adds a reader to w
+// and starts a goroutine to forward the transformed items,
+// which closes the new multichan as described for Pipe.
+// The context argument may be nil.
+func Map[T, U any](ctx context.Context, w *W[T], f func(T) U) *W[U] {
+	out := New[U]()
+	PipeFunc(ctx, w, out, f)
+	return out
+}
+
+// Filter produces a new multichan containing only the items of w for which pred returns true.
+// It adds a reader to w
+// and starts a goroutine to forward the matching items,
+// which closes the new multichan as described for Pipe.
+// The context argument may be nil.
+func Filter[T any](ctx context.Context, w *W[T], pred func(T) bool) *W[T] {
+	out := New[T]()
+	forward(ctx, w.Reader(), out, func(val T) (T, bool) { return val, pred(val) })
+	return out
+}
+
+// Scan produces a new multichan of running aggregates of the items of w.
+// For each item read from w,
+// it computes a new accumulator value with f,
+// starting from init,
+// and writes that value to the new multichan.
+// It adds a reader to w
+// and starts a goroutine to forward the accumulator values,
+// which closes the new multichan as described for Pipe.
+// The context argument may be nil.
+func Scan[T, A any](ctx context.Context, w *W[T], init A, f func(A, T) A) *W[A] {
+	var (
+		out = New[A]()
+		acc = init
+	)
+	forward(ctx, w.Reader(), out, func(val T) (A, bool) {
+		acc = f(acc, val)
+		return acc, true
+	})
+	return out
+}
@@ -0,0 +1,152 @@
+package multichan
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestPipe(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	src := New[int]()
+	dst := New[int]()
+	r := dst.Reader()
+
+	Pipe(context.Background(), src, dst)
+
+	src.WriteBatch([]int{1, 2, 3})
+	src.CloseWithError(errBoom)
+
+	got, err := r.Drain(nil)
+	if !errors.Is(err, errBoom) {
+		t.Errorf("got error %v, want %v", err, errBoom)
+	}
+	if !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("got %v, want [1, 2, 3]", got)
+	}
+}
+
+func TestPipeFunc(t *testing.T) {
+	src := New[int]()
+	dst := New[string]()
+	r := dst.Reader()
+
+	PipeFunc(nil, src, dst, strconv.Itoa)
+
+	src.WriteBatch([]int{1, 2, 3})
+	src.Close()
+
+	got, err := r.Drain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{"1", "2", "3"}) {
+		t.Errorf("got %v, want [1 2 3]", got)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	var (
+		w1 = New[int]()
+		w2 = New[int]()
+		m  = Merge(nil, w1, w2)
+		r  = m.Reader()
+	)
+
+	w1.WriteBatch([]int{1, 2})
+	w2.WriteBatch([]int{3, 4})
+	w1.Close()
+	w2.Close()
+
+	got, err := r.Drain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Ints(got)
+	if !reflect.DeepEqual(got, []int{1, 2, 3, 4}) {
+		t.Errorf("got %v, want [1 2 3 4]", got)
+	}
+}
+
+func TestZip(t *testing.T) {
+	var (
+		a = New[int]()
+		b = New[string]()
+		z = Zip(nil, a, b)
+		r = z.Reader()
+	)
+
+	a.WriteBatch([]int{1, 2, 3})
+	b.WriteBatch([]string{"x", "y"})
+	b.Close()
+
+	got, err := r.Drain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Pair[int, string]{{1, "x"}, {2, "y"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestMap(t *testing.T) {
+	var (
+		w = New[int]()
+		m = Map(nil, w, func(x int) int { return x * x })
+		r = m.Reader()
+	)
+
+	w.WriteBatch([]int{1, 2, 3})
+	w.Close()
+
+	got, err := r.Drain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []int{1, 4, 9}) {
+		t.Errorf("got %v, want [1 4 9]", got)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	var (
+		w = New[int]()
+		f = Filter(nil, w, func(x int) bool { return x%2 == 1 })
+		r = f.Reader()
+	)
+
+	w.WriteBatch([]int{1, 2, 3, 4, 5})
+	w.Close()
+
+	got, err := r.Drain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []int{1, 3, 5}) {
+		t.Errorf("got %v, want [1 3 5]", got)
+	}
+}
+
+func TestScan(t *testing.T) {
+	var (
+		w = New[int]()
+		s = Scan(nil, w, 0, func(sum, x int) int { return sum + x })
+		r = s.Reader()
+	)
+
+	w.WriteBatch([]int{1, 2, 3, 4})
+	w.Close()
+
+	got, err := r.Drain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []int{1, 3, 6, 10}) {
+		t.Errorf("got %v, want [1 3 6 10]", got)
+	}
+}
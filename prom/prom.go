@@ -0,0 +1,102 @@
+// Package prom exposes multichan metrics to Prometheus.
+package prom
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/bobg/multichan"
+)
+
+// Source is a source of multichan metrics.
+// It is satisfied by *multichan.W[T] for any T.
+type Source interface {
+	Stats() multichan.Stats
+}
+
+// Collector is a prometheus.Collector
+// reporting the state of one or more named multichans.
+// Each metric has a "multichan" label giving the name.
+//
+// The metrics are:
+//
+//   - multichan_queue_depth: the number of items retained (see multichan.W.Len)
+//   - multichan_items_written_total: the number of items written
+//   - multichan_items_dropped_total: the number of items dropped before every reader consumed them
+//   - multichan_readers: the number of readers
+//   - multichan_max_reader_lag: the greatest number of items available to any one reader
+//
+// The write rate is the rate of multichan_items_written_total.
+type Collector struct {
+	mu      sync.Mutex
+	sources map[string]Source
+
+	depth, written, dropped, readers, maxLag *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
+
+// NewCollector produces a new Collector with no multichans.
+// Add some with Add,
+// and register the collector with prometheus.Register or a prometheus.Registry.
+func NewCollector() *Collector {
+	labels := []string{"multichan"}
+	return &Collector{
+		sources: make(map[string]Source),
+		depth:   prometheus.NewDesc("multichan_queue_depth", "Number of items retained by the multichan.", labels, nil),
+		written: prometheus.NewDesc("multichan_items_written_total", "Number of items written to the multichan.", labels, nil),
+		dropped: prometheus.NewDesc("multichan_items_dropped_total", "Number of items dropped before every reader consumed them.", labels, nil),
+		readers: prometheus.NewDesc("multichan_readers", "Number of readers of the multichan.", labels, nil),
+		maxLag:  prometheus.NewDesc("multichan_max_reader_lag", "Greatest number of items available to any one reader.", labels, nil),
+	}
+}
+
+// Add adds a multichan to c under the given name,
+// replacing any previously added under that name.
+func (c *Collector) Add(name string, src Source) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sources[name] = src
+}
+
+// Remove removes the multichan with the given name from c.
+func (c *Collector) Remove(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.sources, name)
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.depth
+	ch <- c.written
+	ch <- c.dropped
+	ch <- c.readers
+	ch <- c.maxLag
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	names := make([]string, 0, len(c.sources))
+	for name := range c.sources {
+		names = append(names, name)
+	}
+	sources := make([]Source, len(names))
+	sort.Strings(names)
+	for i, name := range names {
+		sources[i] = c.sources[name]
+	}
+	c.mu.Unlock()
+
+	for i, name := range names {
+		s := sources[i].Stats()
+		ch <- prometheus.MustNewConstMetric(c.depth, prometheus.GaugeValue, float64(s.Len), name)
+		ch <- prometheus.MustNewConstMetric(c.written, prometheus.CounterValue, float64(s.Written), name)
+		ch <- prometheus.MustNewConstMetric(c.dropped, prometheus.CounterValue, float64(s.Dropped), name)
+		ch <- prometheus.MustNewConstMetric(c.readers, prometheus.GaugeValue, float64(len(s.Readers)), name)
+		ch <- prometheus.MustNewConstMetric(c.maxLag, prometheus.GaugeValue, float64(s.MaxLag()), name)
+	}
+}
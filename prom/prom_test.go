@@ -0,0 +1,41 @@
+package prom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/bobg/multichan"
+)
+
+func TestCollector(t *testing.T) {
+	w := multichan.New[int](multichan.Capacity(2, multichan.DropNewest))
+	r := w.Reader()
+	defer r.Dispose()
+	w.WriteBatch([]int{1, 2, 3})
+
+	c := NewCollector()
+	c.Add("test", w)
+
+	const want = `
+# HELP multichan_items_dropped_total Number of items dropped before every reader consumed them.
+# TYPE multichan_items_dropped_total counter
+multichan_items_dropped_total{multichan="test"} 1
+# HELP multichan_items_written_total Number of items written to the multichan.
+# TYPE multichan_items_written_total counter
+multichan_items_written_total{multichan="test"} 2
+# HELP multichan_max_reader_lag Greatest number of items available to any one reader.
+# TYPE multichan_max_reader_lag gauge
+multichan_max_reader_lag{multichan="test"} 2
+# HELP multichan_queue_depth Number of items retained by the multichan.
+# TYPE multichan_queue_depth gauge
+multichan_queue_depth{multichan="test"} 2
+# HELP multichan_readers Number of readers of the multichan.
+# TYPE multichan_readers gauge
+multichan_readers{multichan="test"} 1
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,98 @@
+package multichan
+
+// chunkSize is the number of items in each chunk of a queue.
+const chunkSize = 128
+
+// queue is the storage for a multichan's items:
+// a sequence of fixed-size chunks.
+// Appending never copies existing items,
+// and removing items from the front releases each chunk as soon as it is emptied.
+type queue[T any] struct {
+	chunks [][]item[T]
+	head   int // the index in chunks[0] of the first item
+	n      int // the number of items
+
+	spare []item[T] // an emptied chunk kept for reuse
+}
+
+func (q *queue[T]) len() int {
+	return q.n
+}
+
+// at returns a pointer to the item at index i,
+// which must be in the range [0, q.len()).
+func (q *queue[T]) at(i int) *item[T] {
+	i += q.head
+	return &q.chunks[i/chunkSize][i%chunkSize]
+}
+
+// push appends it to q.
+func (q *queue[T]) push(it item[T]) {
+	i := q.head + q.n
+	if i == len(q.chunks)*chunkSize {
+		chunk := q.spare
+		q.spare = nil
+		if chunk == nil {
+			chunk = make([]item[T], chunkSize)
+		}
+		q.chunks = append(q.chunks, chunk)
+	}
+	q.chunks[i/chunkSize][i%chunkSize] = it
+	q.n++
+}
+
+// dropFront removes the first n items from q.
+func (q *queue[T]) dropFront(n int) {
+	if n == 0 {
+		return
+	}
+	var zero item[T]
+	for i := 0; i < n; i++ {
+		*q.at(i) = zero // allow the garbage collector to reclaim the value
+	}
+	q.head += n
+	q.n -= n
+
+	k := q.head / chunkSize
+	if q.n == 0 {
+		// Keep the last chunk in place.
+		k = len(q.chunks) - 1
+		q.head = 0
+	} else {
+		q.head %= chunkSize
+	}
+	if k > 0 {
+		q.release(q.chunks[:k])
+		q.chunks = append(q.chunks[:0], q.chunks[k:]...)
+		clear(q.chunks[len(q.chunks) : len(q.chunks)+k])
+	}
+}
+
+// truncate removes all but the first n items from q.
+func (q *queue[T]) truncate(n int) {
+	var zero item[T]
+	for i := n; i < q.n; i++ {
+		*q.at(i) = zero
+	}
+	q.n = n
+	if n == 0 {
+		q.head = 0
+	}
+
+	k := (q.head + n + chunkSize - 1) / chunkSize
+	if k == 0 {
+		k = 1
+	}
+	if k < len(q.chunks) {
+		q.release(q.chunks[k:])
+		clear(q.chunks[k:])
+		q.chunks = q.chunks[:k]
+	}
+}
+
+// release keeps one of the given emptied chunks for reuse.
+func (q *queue[T]) release(chunks [][]item[T]) {
+	if len(chunks) > 0 && q.spare == nil {
+		q.spare = chunks[0]
+	}
+}
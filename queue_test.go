@@ -0,0 +1,51 @@
+package multichan
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestQueue(t *testing.T) {
+	var (
+		q     queue[int]
+		model []int
+		rng   = rand.New(rand.NewSource(1))
+		next  int
+	)
+
+	check := func(step int) {
+		if q.len() != len(model) {
+			t.Fatalf("step %d: got len %d, want %d", step, q.len(), len(model))
+		}
+		for i, want := range model {
+			if got := q.at(i).val; got != want {
+				t.Fatalf("step %d: item %d is %d, want %d", step, i, got, want)
+			}
+		}
+		if len(q.chunks)*chunkSize > q.head+q.n+chunkSize {
+			t.Fatalf("step %d: %d chunks retained for %d items", step, len(q.chunks), q.n)
+		}
+	}
+
+	for step := 0; step < 2000; step++ {
+		switch rng.Intn(4) {
+		case 0, 1:
+			for n := rng.Intn(3 * chunkSize); n > 0; n-- {
+				q.push(item[int]{val: next})
+				model = append(model, next)
+				next++
+			}
+
+		case 2:
+			n := rng.Intn(len(model) + 1)
+			q.dropFront(n)
+			model = model[n:]
+
+		case 3:
+			n := rng.Intn(len(model) + 1)
+			q.truncate(n)
+			model = model[:n]
+		}
+		check(step)
+	}
+}
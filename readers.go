@@ -0,0 +1,31 @@
+package multichan
+
+// readerHeap is a min-heap of readers ordered by position, implementing heap.Interface.
+// It lets a multichan find its slowest reader in constant time
+// and update a reader's position in logarithmic time (see setPos).
+type readerHeap[T any] []*reader[T]
+
+func (h readerHeap[T]) Len() int           { return len(h) }
+func (h readerHeap[T]) Less(i, j int) bool { return h[i].pos < h[j].pos }
+
+func (h readerHeap[T]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *readerHeap[T]) Push(x any) {
+	r := x.(*reader[T])
+	r.index = len(*h)
+	*h = append(*h, r)
+}
+
+func (h *readerHeap[T]) Pop() any {
+	old := *h
+	n := len(old)
+	r := old[n-1]
+	old[n-1] = nil // allow the garbage collector to reclaim the reader
+	r.index = -1
+	*h = old[:n-1]
+	return r
+}
@@ -0,0 +1,240 @@
+// Package redis connects multichans to Redis
+// using github.com/redis/go-redis/v9.
+//
+// Pub/Sub channels (see Publish and Subscribe)
+// carry items to whichever processes are subscribed at the time,
+// with no persistence or acknowledgment.
+// Streams (see StreamSink and StreamSource)
+// store items durably in Redis,
+// and consumers resume where they left off.
+//
+// Items are encoded with a multichan.Codec,
+// such as multichan.GobCodec or multichan.JSONCodec.
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/bobg/multichan"
+)
+
+// maxBatch is the largest number of items sent to or fetched from Redis at once.
+const maxBatch = 100
+
+// valField is the field of a stream entry holding the encoded item.
+const valField = "val"
+
+// pollInterval is the longest that StreamSource waits for new stream entries
+// before checking whether its context is canceled.
+// (Blocking Redis commands do not respond to the cancellation of their contexts.)
+const pollInterval = time.Second
+
+// Publish reads items from r,
+// encodes each with codec,
+// and publishes it on the given Redis Pub/Sub channel.
+//
+// Publish runs until r has consumed the last item of its closed multichan,
+// returning r's error if any (see multichan.R.Err),
+// or until the context is canceled,
+// or until encoding or publishing fails,
+// in which case it returns the error.
+func Publish[T any](ctx context.Context, r *multichan.R[T], client redis.UniversalClient, channel string, codec multichan.Codec[T]) error {
+	for {
+		val, ok := r.Read(ctx)
+		if !ok {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return r.Err()
+		}
+		data, err := codec.Encode(val)
+		if err != nil {
+			return fmt.Errorf("encoding item: %w", err)
+		}
+		if err := client.Publish(ctx, channel, data).Err(); err != nil {
+			return fmt.Errorf("publishing item: %w", err)
+		}
+	}
+}
+
+// Subscribe subscribes to the given Redis Pub/Sub channel,
+// decodes each message received with codec,
+// and writes the item to w.
+// Messages published while Subscribe is not running are not received.
+// If the connection to Redis fails,
+// the client reconnects and resubscribes,
+// and messages published in between are lost.
+//
+// Subscribe runs until the context is canceled,
+// or until w is closed,
+// or until subscribing or decoding fails,
+// in which case it returns the error.
+// It does not close w.
+func Subscribe[T any](ctx context.Context, client redis.UniversalClient, channel string, w *multichan.W[T], codec multichan.Codec[T]) error {
+	ps := client.Subscribe(ctx, channel)
+	defer ps.Close()
+
+	// Wait for confirmation of the subscription.
+	if _, err := ps.Receive(ctx); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("subscribing: %w", err)
+	}
+
+	ch := ps.Channel()
+	for {
+		var msg *redis.Message
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg = <-ch:
+		}
+		val, err := codec.Decode([]byte(msg.Payload))
+		if err != nil {
+			return fmt.Errorf("decoding message: %w", err)
+		}
+		if err := w.WriteContext(ctx, val); err != nil {
+			return err
+		}
+	}
+}
+
+// StreamSink reads items from r,
+// encodes each with codec,
+// and adds it to the given Redis stream,
+// in the "val" field of a new entry.
+//
+// If r was created with multichan.W.ResumeReader,
+// its position is committed (see multichan.R.Commit)
+// after each batch of items is added,
+// so that a sink resumed with the same name
+// continues with the first item not added to the stream.
+// (If the process stops after adding a batch but before committing,
+// the batch is added again.)
+//
+// StreamSink runs until r has consumed the last item of its closed multichan,
+// returning r's error if any (see multichan.R.Err),
+// or until the context is canceled,
+// or until encoding, adding, or committing fails,
+// in which case it returns the error.
+func StreamSink[T any](ctx context.Context, r *multichan.R[T], client redis.UniversalClient, stream string, codec multichan.Codec[T]) error {
+	for {
+		vals := r.ReadN(ctx, maxBatch)
+		if len(vals) == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return r.Err()
+		}
+		pipe := client.Pipeline()
+		for _, val := range vals {
+			data, err := codec.Encode(val)
+			if err != nil {
+				return fmt.Errorf("encoding item: %w", err)
+			}
+			pipe.XAdd(ctx, &redis.XAddArgs{
+				Stream: stream,
+				Values: []any{valField, data},
+			})
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return fmt.Errorf("adding stream entries: %w", err)
+		}
+		if err := r.Commit(); err != nil && !errors.Is(err, multichan.ErrNoOffsetStore) {
+			return fmt.Errorf("committing reader position: %w", err)
+		}
+	}
+}
+
+// StreamSource reads entries from the given Redis stream
+// as the named consumer in the named consumer group,
+// creating the group (and the stream) if necessary,
+// decodes the "val" field of each with codec,
+// and writes the item to w.
+//
+// Entries are acknowledged (see XACK) in batches,
+// once every reader of w has consumed their items
+// (see multichan.W.Flush).
+// When StreamSource starts,
+// it first rereads the entries delivered to the consumer but not acknowledged,
+// so that a source resumed with the same group and consumer
+// continues with the first entry not consumed by every reader.
+//
+// StreamSource runs until the context is canceled,
+// or until w is closed,
+// or until reading, decoding, or acknowledging fails,
+// in which case it returns the error.
+// It does not close w.
+func StreamSource[T any](ctx context.Context, client redis.UniversalClient, stream, group, consumer string, w *multichan.W[T], codec multichan.Codec[T]) error {
+	err := client.XGroupCreateMkStream(ctx, stream, group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("creating consumer group: %w", err)
+	}
+
+	// Start with the pending entries, then switch to new ones.
+	id := "0"
+
+	for {
+		res, err := client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    group,
+			Consumer: consumer,
+			Streams:  []string{stream, id},
+			Count:    maxBatch,
+			Block:    pollInterval,
+		}).Result()
+		if err == redis.Nil {
+			err = nil
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("reading stream: %w", err)
+		}
+
+		var msgs []redis.XMessage
+		if len(res) > 0 {
+			msgs = res[0].Messages
+		}
+		if len(msgs) == 0 && id == "0" {
+			id = ">"
+			continue
+		}
+
+		ids := make([]string, 0, len(msgs))
+		for _, msg := range msgs {
+			data, ok := msg.Values[valField].(string)
+			if !ok {
+				return fmt.Errorf("stream entry %s has no %q field", msg.ID, valField)
+			}
+			val, err := codec.Decode([]byte(data))
+			if err != nil {
+				return fmt.Errorf("decoding stream entry %s: %w", msg.ID, err)
+			}
+			if err := w.WriteContext(ctx, val); err != nil {
+				return err
+			}
+			ids = append(ids, msg.ID)
+		}
+		if len(ids) == 0 {
+			continue
+		}
+
+		if err := w.Flush(ctx); err != nil {
+			return err
+		}
+		if err := client.XAck(ctx, stream, group, ids...).Err(); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("acknowledging stream entries: %w", err)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package redis
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/bobg/multichan"
+)
+
+func newClient(t *testing.T) redis.UniversalClient {
+	t.Helper()
+	s := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestPubSub(t *testing.T) {
+	client := newClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var codec multichan.JSONCodec[int]
+
+	remote := multichan.New[int]()
+	rr := remote.Reader()
+	done := make(chan error, 1)
+	go func() {
+		done <- Subscribe(ctx, client, "ch", remote, codec)
+	}()
+
+	// Wait for the subscription.
+	for {
+		n, err := client.PubSubNumSub(ctx, "ch").Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n["ch"] > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	w := multichan.New[int]()
+	r := w.Reader()
+	w.WriteBatch([]int{1, 2, 3})
+	w.Close()
+	if err := Publish(ctx, r, client, "ch", codec); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []int
+	for len(got) < 3 {
+		val, ok := rr.Read(ctx)
+		if !ok {
+			t.Fatal("reader closed early")
+		}
+		got = append(got, val)
+	}
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestStreams(t *testing.T) {
+	client := newClient(t)
+	ctx := context.Background()
+
+	var codec multichan.JSONCodec[int]
+
+	store := multichan.DirOffsetStore(t.TempDir())
+	w := multichan.New[int](multichan.Retain(10))
+	r, err := w.ResumeReader("sink", store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteBatch([]int{1, 2, 3, 4})
+	w.Close()
+	if err := StreamSink(ctx, r, client, "stream", codec); err != nil {
+		t.Fatal(err)
+	}
+	if offset, ok, err := store.LoadOffset("sink"); err != nil || !ok || offset != 4 {
+		t.Errorf("got committed offset %d, %v, %v; want 4, true, nil", offset, ok, err)
+	}
+
+	// Consume two items, then stop before the third is acknowledged.
+	w1 := multichan.New[int]()
+	r1 := w1.Reader()
+	ctx1, cancel1 := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() {
+		done <- StreamSource(ctx1, client, "stream", "group", "consumer", w1, codec)
+	}()
+	var got []int
+	for len(got) < 2 {
+		val, _ := r1.Read(ctx)
+		got = append(got, val)
+	}
+	if want := []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	cancel1()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+
+	// A resumed source rereads the unacknowledged entries.
+	w2 := multichan.New[int]()
+	r2 := w2.Reader()
+	ctx2, cancel2 := context.WithCancel(ctx)
+	go func() {
+		done <- StreamSource(ctx2, client, "stream", "group", "consumer", w2, codec)
+	}()
+	got = nil
+	for len(got) < 4 {
+		val, _ := r2.Read(ctx)
+		got = append(got, val)
+	}
+	if want := []int{1, 2, 3, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	cancel2()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,122 @@
+package multichan
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Requester carries requests of type Q to responders
+// and their replies, of type P, back to the requesters,
+// for in-process RPC over multichans.
+// Each request is broadcast to every responder
+// (see Requests)
+// and may be answered by any of them
+// (see Reply).
+//
+// Create one with NewRequester.
+type Requester[Q, P any] struct {
+	requests *W[Request[Q]]
+	replies  *Demux[reply[P], uint64]
+	nextID   atomic.Uint64
+}
+
+// Request is a request carried by a Requester,
+// with the ID that correlates it with its reply.
+type Request[Q any] struct {
+	ID  uint64
+	Val Q
+}
+
+// A reply is the answer to the request with the given ID.
+type reply[P any] struct {
+	id  uint64
+	val P
+	err error
+}
+
+// NewRequester produces a new Requester
+// whose multichan of requests is created with New(opts...).
+func NewRequester[Q, P any](opts ...Option) *Requester[Q, P] {
+	return &Requester[Q, P]{
+		requests: New[Request[Q]](opts...),
+		replies:  NewDemux(func(rep reply[P]) uint64 { return rep.id }),
+	}
+}
+
+// Call sends a request and waits for its reply,
+// returning the value and error given in Reply.
+//
+// If the context is canceled first
+// (e.g. because it has a timeout, see context.WithTimeout),
+// Call returns the context's error,
+// and any later reply to the request is discarded.
+// If the Requester is closed first,
+// Call returns ErrClosed.
+// The context argument may be nil.
+func (q *Requester[Q, P]) Call(ctx context.Context, val Q) (P, error) {
+	var zero P
+
+	id := q.nextID.Add(1)
+	r := q.replies.Stream(id)
+	defer r.Dispose()
+
+	if err := q.requests.WriteContext(ctx, Request[Q]{ID: id, Val: val}); err != nil {
+		return zero, err
+	}
+	rep, ok := r.Read(ctx)
+	if !ok {
+		if ctx != nil && ctx.Err() != nil {
+			return zero, ctx.Err()
+		}
+		return zero, ErrClosed
+	}
+	return rep.val, rep.err
+}
+
+// Requests adds a new reader to the multichan of requests and returns it.
+// A responder reads requests from it
+// and answers each one with Reply.
+// See W.Reader.
+func (q *Requester[Q, P]) Requests(opts ...ReaderOption) *R[Request[Q]] {
+	return q.requests.Reader(opts...)
+}
+
+// Reply answers the request with the given ID,
+// causing the call to Call that sent it
+// to return the given value and error.
+// If that call has already returned,
+// or if another reply to the request was sent first,
+// the reply is discarded.
+func (q *Requester[Q, P]) Reply(id uint64, val P, err error) error {
+	return q.replies.Write(reply[P]{id: id, val: val, err: err})
+}
+
+// Serve is a responder
+// that answers each request with the result of calling f on it.
+// It runs until q is closed or the context is canceled.
+// Requests are handled one at a time,
+// with the context passed to f.
+// The context argument may be nil.
+func (q *Requester[Q, P]) Serve(ctx context.Context, f func(context.Context, Q) (P, error)) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	r := q.Requests()
+	defer r.Dispose()
+
+	for {
+		req, ok := r.Read(ctx)
+		if !ok {
+			return
+		}
+		val, err := f(ctx, req.Val)
+		q.Reply(req.ID, val, err)
+	}
+}
+
+// Close closes the Requester,
+// causing pending and future calls to Call to return ErrClosed.
+func (q *Requester[Q, P]) Close() {
+	q.requests.Close()
+	q.replies.Close()
+}
@@ -0,0 +1,69 @@
+package multichan
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRequester(t *testing.T) {
+	q := NewRequester[int, string]()
+	defer q.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go q.Serve(ctx, func(_ context.Context, n int) (string, error) {
+		if n < 0 {
+			return "", errors.New("negative")
+		}
+		return strconv.Itoa(n), nil
+	})
+
+	// Wait for the responder.
+	for q.requests.NumReaders() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	got, err := q.Call(ctx, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "7" {
+		t.Errorf("got %q, want \"7\"", got)
+	}
+
+	if _, err := q.Call(ctx, -1); err == nil || err.Error() != "negative" {
+		t.Errorf("got error %v, want negative", err)
+	}
+}
+
+func TestRequesterTimeout(t *testing.T) {
+	q := NewRequester[int, int]()
+	r := q.Requests()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := q.Call(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// A late reply is discarded.
+	req, _ := r.NBRead()
+	if err := q.Reply(req.ID, 2, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := q.Call(nil, 3)
+		done <- err
+	}()
+	r.Read(nil)
+	q.Close()
+	if err := <-done; err != ErrClosed {
+		t.Errorf("got %v, want %v", err, ErrClosed)
+	}
+}
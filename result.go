@@ -0,0 +1,86 @@
+package multichan
+
+import "context"
+
+// WriteError adds a non-fatal error to the multichan,
+// e.g. to report an item a producer failed to produce,
+// without ending the stream as CloseWithError does.
+// The error takes its place in the stream like an item,
+// subject to the same capacity and retention policies,
+// but only readers created with ReceiveErrors see it,
+// with R.ReadResult.
+// (Errors are also not reported to Instrument hooks,
+// OnDrop, or DeadLetter functions,
+// or deduplicated with DedupConsecutive.)
+//
+// A nil error is ignored.
+func (w *W[T]) WriteError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return w.write(nil, item[T]{err: err}, w.ttl)
+}
+
+// ReadResult is the type of value returned by R.ReadResult:
+// either an item or an error written with W.WriteError.
+type ReadResult[T any] struct {
+	Val T
+	Err error
+}
+
+// ReadResult is like Read,
+// but for a reader created with ReceiveErrors
+// returns the errors written with W.WriteError
+// as well as the items of the multichan,
+// in the order in which they were written.
+// (Read and the other methods for reading
+// return an error as the zero value of T.)
+func (r *R[T]) ReadResult(ctx context.Context) (ReadResult[T], bool) {
+	it, ok := r.read(ctx)
+	return ReadResult[T]{Val: it.val, Err: it.err}, ok
+}
+
+// ErrorReader returns a reader of the errors written to w with W.WriteError,
+// and none of its items.
+// It adds a reader to w with ReceiveErrors
+// and starts a goroutine forwarding the errors to the returned reader,
+// which ends when w is closed.
+// Disposing of the returned reader stops the forwarding
+// and disposes of the reader of w.
+func (w *W[T]) ErrorReader() *R[error] {
+	var (
+		in   = w.Reader(ReceiveErrors(), Where(func(T) bool { return false }))
+		out  = New[error]()
+		outR = out.Reader()
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		// Stop when outR is disposed of.
+		select {
+		case <-out.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	go func() {
+		defer cancel()
+		defer in.Dispose()
+
+		for {
+			res, ok := in.ReadResult(ctx)
+			if !ok {
+				break
+			}
+			if out.WriteContext(ctx, res.Err) != nil {
+				return
+			}
+		}
+		if ctx.Err() == nil {
+			out.CloseWithError(in.Err())
+		}
+	}()
+
+	return outR
+}
@@ -0,0 +1,91 @@
+package multichan
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestWriteError(t *testing.T) {
+	errTest := errors.New("test")
+
+	w := New[int]()
+	plain := w.Reader()
+	r := w.Reader(ReceiveErrors())
+	errs := w.ErrorReader()
+
+	w.Write(1)
+	w.WriteError(errTest)
+	w.Write(2)
+	w.Close()
+
+	var got []ReadResult[int]
+	for {
+		res, ok := r.ReadResult(nil)
+		if !ok {
+			break
+		}
+		got = append(got, res)
+	}
+	want := []ReadResult[int]{{Val: 1}, {Err: errTest}, {Val: 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if vals, err := plain.Drain(nil); err != nil || !reflect.DeepEqual(vals, []int{1, 2}) {
+		t.Errorf("got %v, %v; want [1 2], nil", vals, err)
+	}
+
+	if got, err := errs.Drain(nil); err != nil || !reflect.DeepEqual(got, []error{errTest}) {
+		t.Errorf("got %v, %v; want [%v], nil", got, err, errTest)
+	}
+}
+
+func TestWriteErrorRetention(t *testing.T) {
+	// Errors are not retained on behalf of readers that do not receive them.
+	w := New[int]()
+	r := w.Reader()
+	w.WriteError(errors.New("test"))
+	if n := w.Len(); n != 0 {
+		t.Errorf("got length %d, want 0", n)
+	}
+	if n := r.Pending(); n != 0 {
+		t.Errorf("got %d pending, want 0", n)
+	}
+
+	// A new reader skips the errors already in the multichan.
+	w = New[int](Retain(3))
+	w.Write(1)
+	w.WriteError(errors.New("test"))
+	w.Write(2)
+	w.Close()
+	if got, err := w.Reader(FromEarliest()).Drain(nil); err != nil || !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("got %v, %v; want [1 2], nil", got, err)
+	}
+}
+
+func TestWriteErrorHooks(t *testing.T) {
+	var (
+		h     recordingHooks
+		drops int
+	)
+	w := New[int](
+		Capacity(1, DropOldest),
+		Instrument[int](&h),
+		OnDrop(func(int, ReaderInfo, DropReason) { drops++ }),
+	)
+	r := w.Reader(ReceiveErrors())
+
+	w.WriteError(errors.New("test"))
+	w.Write(1) // drops the error
+	w.WriteError(errors.New("test"))
+	if _, ok := r.ReadResult(nil); !ok {
+		t.Fatal("got no result")
+	}
+	if want := []string{"add ", "write 1@1"}; !reflect.DeepEqual(h.events, want) {
+		t.Errorf("got events %v, want %v", h.events, want)
+	}
+	if drops != 1 {
+		t.Errorf("got %d drops, want 1", drops)
+	}
+}
@@ -0,0 +1,115 @@
+package multichan
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync/atomic"
+)
+
+// Sharded is a multichan whose readers are partitioned among several shards,
+// each an independent W[T] with its own lock,
+// so that very large numbers of readers do not all contend for a single lock.
+// Each item written to a Sharded is written to every shard.
+// Writes are not serialized across shards,
+// so the items of concurrent writes may reach different shards in different orders;
+// the items written by a single goroutine reach every shard in order.
+//
+// Create one with NewSharded.
+type Sharded[T any] struct {
+	shards []*W[T]
+	next   atomic.Uint64 // the number of readers added, which chooses the shard for the next one
+}
+
+// NewSharded produces a new sharded multichan (see Sharded)
+// with the given number of shards,
+// each created with New[T](opts...).
+// A value of shards less than 1 means 1.
+// If the options include Expvar,
+// each shard publishes its statistics under the given name
+// with the suffix "." and the shard's index,
+// as in "name.0", "name.1", and so on.
+func NewSharded[T any](shards int, opts ...Option) *Sharded[T] {
+	if shards < 1 {
+		shards = 1
+	}
+	var conf config
+	for _, opt := range opts {
+		opt(&conf)
+	}
+	s := &Sharded[T]{shards: make([]*W[T], shards)}
+	for i := range s.shards {
+		shardOpts := opts
+		if conf.expvar != "" {
+			shardOpts = append(slices.Clip(opts), Expvar(fmt.Sprintf("%s.%d", conf.expvar, i)))
+		}
+		s.shards[i] = New[T](shardOpts...)
+	}
+	return s
+}
+
+// Write adds an item to the multichan.
+// It is like W.Write,
+// but writes the item to every shard.
+// If a shard applies the Block overflow policy (see Capacity),
+// Write blocks until that shard has room.
+func (s *Sharded[T]) Write(val T) error {
+	return s.WriteContext(nil, val)
+}
+
+// WriteContext is like Write,
+// but if it blocks waiting for room in a shard,
+// it gives up when its context is canceled,
+// returning the context's error
+// (in which case some shards may have received the item and others not).
+// The context argument may be nil.
+func (s *Sharded[T]) WriteContext(ctx context.Context, val T) error {
+	for _, w := range s.shards {
+		if err := w.WriteContext(ctx, val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteBatch adds the given items to the multichan, in order.
+// It is like W.WriteBatch,
+// but writes the items to every shard.
+func (s *Sharded[T]) WriteBatch(vals []T) error {
+	for _, w := range s.shards {
+		if err := w.WriteBatch(vals); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes every shard (see W.Close).
+func (s *Sharded[T]) Close() {
+	s.CloseWithError(nil)
+}
+
+// CloseWithError closes every shard with the given error (see W.CloseWithError).
+func (s *Sharded[T]) CloseWithError(err error) {
+	for _, w := range s.shards {
+		w.CloseWithError(err)
+	}
+}
+
+// Reader adds a new reader to one of the multichan's shards,
+// chosen in rotation,
+// and returns it.
+// See W.Reader.
+func (s *Sharded[T]) Reader(opts ...ReaderOption) *R[T] {
+	n := s.next.Add(1) - 1
+	return s.shards[n%uint64(len(s.shards))].Reader(opts...)
+}
+
+// NumReaders tells how many readers the multichan has across all its shards.
+func (s *Sharded[T]) NumReaders() int {
+	var n int
+	for _, w := range s.shards {
+		n += w.NumReaders()
+	}
+	return n
+}
@@ -0,0 +1,75 @@
+package multichan
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSharded(t *testing.T) {
+	s := NewSharded[int](4)
+
+	const numReaders = 10
+
+	var (
+		wg  sync.WaitGroup
+		got = make([][]int, numReaders)
+	)
+	for i := 0; i < numReaders; i++ {
+		r := s.Reader()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got[i], _ = r.Drain(nil)
+		}()
+	}
+	if n := s.NumReaders(); n != numReaders {
+		t.Errorf("got %d readers, want %d", n, numReaders)
+	}
+
+	s.WriteBatch([]int{1, 2})
+	s.Write(3)
+	s.Close()
+	wg.Wait()
+
+	for i, vals := range got {
+		if !reflect.DeepEqual(vals, []int{1, 2, 3}) {
+			t.Errorf("reader %d got %v, want [1 2 3]", i, vals)
+		}
+	}
+}
+
+func TestShardedBlockedWrite(t *testing.T) {
+	s := NewSharded[int](2, Capacity(1, Block))
+	r := s.Reader() // on the first shard
+	defer r.Dispose()
+
+	if err := s.Write(1); err != nil {
+		t.Fatal(err)
+	}
+	errch := make(chan error)
+	go func() { errch <- s.Write(2) }()
+
+	// Wait for the write to block.
+	select {
+	case err := <-errch:
+		t.Fatalf("write did not block (error %v)", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Reader().Dispose()
+		s.Close()
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Reader or Close blocked")
+	}
+	if err := <-errch; err != ErrClosed {
+		t.Errorf("got %v, want %v", err, ErrClosed)
+	}
+}
@@ -0,0 +1,51 @@
+package multichan
+
+import (
+	"context"
+	"os"
+	"os/signal"
+)
+
+// Signals produces a new multichan,
+// created with New[os.Signal](opts...),
+// to which the given incoming signals are written
+// (all incoming signals if none are given, see signal.Notify),
+// so that many components can each watch for e.g. SIGTERM with their own reader.
+//
+// Signals are relayed until the context is canceled,
+// at which point the relaying stops (see signal.Stop)
+// and the multichan is closed with the context's error.
+// Relaying also stops if the multichan is closed,
+// once the next signal arrives.
+//
+// As with any multichan,
+// readers see only the signals written after they are added.
+// To see signals arriving before then,
+// use FromEarliest with a retention policy (see Retain).
+func Signals(ctx context.Context, sigs []os.Signal, opts ...Option) *W[os.Signal] {
+	w := New[os.Signal](opts...)
+
+	ch := make(chan os.Signal, 16)
+	signal.Notify(ch, sigs...)
+
+	go func() {
+		defer signal.Stop(ch)
+
+		for {
+			select {
+			case <-ctx.Done():
+				w.CloseWithError(ctx.Err())
+				return
+			case sig := <-ch:
+				if err := w.WriteContext(ctx, sig); err != nil {
+					if ctx.Err() != nil {
+						w.CloseWithError(ctx.Err())
+					}
+					return
+				}
+			}
+		}
+	}()
+
+	return w
+}
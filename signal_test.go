@@ -0,0 +1,35 @@
+//go:build unix
+
+package multichan
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestSignals(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w := Signals(ctx, []os.Signal{syscall.SIGUSR1})
+	r1, r2 := w.Reader(), w.Reader()
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []*R[os.Signal]{r1, r2} {
+		if sig, ok := r.Read(ctx); !ok || sig != syscall.SIGUSR1 {
+			t.Errorf("got %v, %v; want %v, true", sig, ok, syscall.SIGUSR1)
+		}
+	}
+
+	cancel()
+	if _, ok := r1.Read(nil); ok {
+		t.Error("got an item, want end of stream")
+	}
+	if err := r1.Err(); err != context.Canceled {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+}
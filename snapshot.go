@@ -0,0 +1,204 @@
+package multichan
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// snapshotMagic begins every snapshot written by W.Save.
+const snapshotMagic = "multichan snapshot 1\n"
+
+// The closed-state values in a snapshot.
+const (
+	snapshotOpen = iota
+	snapshotClosed
+	snapshotClosedWithError
+)
+
+// Save writes a snapshot of w to out,
+// from which Load can reconstruct it,
+// e.g. after the process restarts.
+// The snapshot contains the items w retains,
+// encoded with codec,
+// their stream offsets and priorities (see Prioritized),
+// the offset of the next item to be written,
+// and whether w is closed
+// (and with what error, see W.CloseWithError).
+//
+// It does not contain w's readers,
+// which can be recreated at their former positions with W.ReaderAt,
+// nor the expiration times of items (see TTL),
+// nor errors written with W.WriteError.
+//
+// w is locked while Save runs,
+// blocking its writers and readers.
+func (w *W[T]) Save(out io.Writer, codec Codec[T]) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	bw := bufio.NewWriter(out)
+	buf := []byte(snapshotMagic)
+	buf = binary.AppendVarint(buf, w.offset)
+	buf = binary.AppendVarint(buf, w.next)
+	switch {
+	case !w.closed:
+		buf = append(buf, snapshotOpen)
+	case w.err == nil:
+		buf = append(buf, snapshotClosed)
+	default:
+		buf = append(buf, snapshotClosedWithError)
+		buf = appendBytes(buf, []byte(w.err.Error()))
+	}
+	var n int
+	for i := 0; i < w.items.len(); i++ {
+		if it := w.items.at(i); !it.dead && it.err == nil {
+			n++
+		}
+	}
+	buf = binary.AppendUvarint(buf, uint64(n))
+	if _, err := bw.Write(buf); err != nil {
+		return err
+	}
+
+	for i := 0; i < w.items.len(); i++ {
+		it := w.items.at(i)
+		if it.dead || it.err != nil {
+			continue
+		}
+		data, err := codec.Encode(w.value(it))
+		if err != nil {
+			return fmt.Errorf("encoding item %d: %w", it.offset, err)
+		}
+		buf = binary.AppendVarint(buf[:0], it.offset)
+		buf = binary.AppendVarint(buf, int64(it.priority))
+		buf = appendBytes(buf, data)
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Load reconstructs a multichan from a snapshot written by W.Save.
+// The multichan is created with New[T](opts...),
+// and the items in the snapshot are decoded with codec.
+// Its readers can be recreated at their former positions with W.ReaderAt.
+//
+// The loaded items are retained regardless of the retention policy (see Retain)
+// until the next write to the multichan or disposal of one of its readers,
+// so readers should be recreated before then.
+func Load[T any](in io.Reader, codec Codec[T], opts ...Option) (*W[T], error) {
+	br, ok := in.(io.ByteReader)
+	if !ok {
+		b := bufio.NewReader(in)
+		in, br = b, b
+	}
+
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(in, magic); err != nil || string(magic) != snapshotMagic {
+		return nil, errors.New("not a multichan snapshot")
+	}
+	offset, err := binary.ReadVarint(br)
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+	next, err := binary.ReadVarint(br)
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+	state, err := br.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+	var closeErr error
+	switch state {
+	case snapshotOpen, snapshotClosed:
+	case snapshotClosedWithError:
+		msg, err := readBytes(in, br)
+		if err != nil {
+			return nil, fmt.Errorf("reading snapshot: %w", err)
+		}
+		closeErr = errors.New(string(msg))
+	default:
+		return nil, fmt.Errorf("invalid snapshot state %d", state)
+	}
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+
+	w, err := NewChecked[T](opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.offset, w.next = offset, offset
+	for ; n > 0; n-- {
+		itemOffset, err := binary.ReadVarint(br)
+		if err != nil {
+			return nil, fmt.Errorf("reading snapshot: %w", err)
+		}
+		priority, err := binary.ReadVarint(br)
+		if err != nil {
+			return nil, fmt.Errorf("reading snapshot: %w", err)
+		}
+		data, err := readBytes(in, br)
+		if err != nil {
+			return nil, fmt.Errorf("reading snapshot: %w", err)
+		}
+		if itemOffset < w.next || itemOffset >= next {
+			return nil, fmt.Errorf("snapshot item offset %d out of order", itemOffset)
+		}
+		val, err := codec.Decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("decoding item %d: %w", itemOffset, err)
+		}
+		w.next = itemOffset
+		w.insert(item[T]{val: val, priority: int(priority)}, 0)
+	}
+	w.next = next
+	w.published.Store(next)
+
+	if w.spill != nil {
+		w.spillOver()
+	}
+	if state != snapshotOpen {
+		w.closed = true
+		w.err = closeErr
+		if w.spill != nil {
+			w.spill.retire()
+		}
+	}
+
+	return w, nil
+}
+
+// appendBytes appends to buf the length of data as a uvarint,
+// then data.
+func appendBytes(buf, data []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(data)))
+	return append(buf, data...)
+}
+
+// readBytes reads data written by appendBytes.
+// The arguments must be the same reader.
+func readBytes(in io.Reader, br io.ByteReader) ([]byte, error) {
+	size, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxRecordSize {
+		return nil, errors.New("snapshot record too large")
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(in, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,134 @@
+package multichan
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	w := New[int](Prioritized(), Retain(10))
+	w.WriteBatch([]int{1, 2, 3})
+	w.WriteWithPriority(4, 1)
+
+	buf := new(bytes.Buffer)
+	if err := w.Save(buf, intCodec{}); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := Load[int](buf, intCodec{}, Prioritized())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r1, err := w.ReaderAt(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := w.ReaderAt(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(5); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	cases := []struct {
+		r    *R[int]
+		want []int
+	}{
+		{r: r1, want: []int{4, 2, 3, 5}},
+		{r: r2, want: []int{4, 3, 5}},
+	}
+	for i, c := range cases {
+		got, err := c.r.Drain(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("case %d: got %v, want %v", i+1, got, c.want)
+		}
+	}
+}
+
+func TestSaveLoadClosed(t *testing.T) {
+	w := New[int](Retain(2))
+	w.WriteBatch([]int{1, 2, 3})
+	w.CloseWithError(errors.New("oops"))
+
+	buf := new(bytes.Buffer)
+	if err := w.Save(buf, intCodec{}); err != nil {
+		t.Fatal(err)
+	}
+	w, err := Load[int](buf, intCodec{}, Retain(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !w.Closed() {
+		t.Fatal("loaded multichan is not closed")
+	}
+	if err := w.Write(4); err != ErrClosed {
+		t.Errorf("got %v, want %v", err, ErrClosed)
+	}
+
+	r := w.Reader(FromEarliest())
+	var offsets []int64
+	for {
+		_, offset, ok := r.ReadOffset(nil)
+		if !ok {
+			break
+		}
+		offsets = append(offsets, offset)
+	}
+	if want := []int64{1, 2}; !reflect.DeepEqual(offsets, want) {
+		t.Errorf("got offsets %v, want %v", offsets, want)
+	}
+	if err := r.Err(); err == nil || err.Error() != "oops" {
+		t.Errorf("got error %v, want oops", err)
+	}
+}
+
+func TestSaveLoadErrors(t *testing.T) {
+	w := New[int](Retain(10))
+	w.Write(1)
+	w.WriteError(errors.New("oops"))
+	w.Write(2)
+
+	buf := new(bytes.Buffer)
+	if err := w.Save(buf, intCodec{}); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := Load[int](buf, intCodec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := w.Reader(FromEarliest())
+	w.Close()
+
+	var (
+		got     []int
+		offsets []int64
+	)
+	for {
+		val, offset, ok := r.ReadOffset(nil)
+		if !ok {
+			break
+		}
+		got = append(got, val)
+		offsets = append(offsets, offset)
+	}
+	if want := []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if want := []int64{0, 2}; !reflect.DeepEqual(offsets, want) {
+		t.Errorf("got offsets %v, want %v", offsets, want)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	if _, err := Load[int](bytes.NewReader([]byte("hello")), intCodec{}); err == nil {
+		t.Error("got no error")
+	}
+}
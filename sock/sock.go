@@ -0,0 +1,216 @@
+// Package sock serves multichans over plain stream sockets, such as TCP and Unix-domain sockets,
+// and mirrors them in remote processes.
+//
+// Items are sent as frames,
+// each a four-byte big-endian length followed by that many bytes:
+// the item encoded with a multichan.Codec,
+// such as multichan.GobCodec or multichan.JSONCodec.
+// A frame with the length 0xffffffff (and no data) marks the end of the stream.
+// Clients send nothing.
+package sock
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/bobg/multichan"
+)
+
+// endOfStream is the length in the frame marking the end of the stream.
+const endOfStream = 0xffffffff
+
+// DefaultMaxFrame is the default limit on the size of the frames
+// that Dial accepts from a server (see DialOptions).
+const DefaultMaxFrame = 16 << 20
+
+// DialOptions holds options for Dial.
+type DialOptions struct {
+	// MaxFrame is the largest frame, in bytes,
+	// accepted from the server.
+	// A larger frame is an error,
+	// so that a faulty or malicious server cannot cause a huge allocation.
+	// If it is zero, DefaultMaxFrame is used.
+	MaxFrame int
+}
+
+// Serve accepts connections on ln
+// and streams the items of w to each one
+// through its own reader,
+// created with the given options
+// and disposed of when the connection closes.
+// When w is closed and a reader has consumed the last item,
+// the end of the stream is sent to its client
+// and the connection is closed.
+//
+// Serve runs until the context is canceled,
+// at which point it closes ln and its connections
+// and returns the context's error,
+// or until accepting a connection fails,
+// in which case it returns that error.
+func Serve[T any](ctx context.Context, ln net.Listener, w *multichan.W[T], codec multichan.Codec[T], opts ...multichan.ReaderOption) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		// Create the reader now,
+		// so the client sees every item written after its connection was accepted.
+		r := w.Reader(opts...)
+		go serveConn(ctx, conn, r, codec)
+	}
+}
+
+// serveConn streams the items of r to conn.
+func serveConn[T any](ctx context.Context, conn net.Conn, r *multichan.R[T], codec multichan.Codec[T]) {
+	defer r.Dispose()
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The client sends nothing,
+	// so reading detects the closing of the connection.
+	go func() {
+		io.Copy(io.Discard, conn)
+		cancel()
+	}()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	bw := bufio.NewWriter(conn)
+	for {
+		val, ok := r.Read(ctx)
+		if !ok {
+			if ctx.Err() == nil {
+				bw.Write(binary.BigEndian.AppendUint32(nil, endOfStream))
+				bw.Flush()
+			}
+			return
+		}
+		data, err := codec.Encode(val)
+		if err != nil {
+			return
+		}
+		if _, err := bw.Write(binary.BigEndian.AppendUint32(nil, uint32(len(data)))); err != nil {
+			return
+		}
+		if _, err := bw.Write(data); err != nil {
+			return
+		}
+		if r.Pending() > 0 {
+			// Send more items before flushing.
+			continue
+		}
+		if err := bw.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// Dial connects to a server (see Serve) at the given address on the named network (see net.Dial)
+// and produces a local multichan,
+// created with multichan.New(opts...),
+// that mirrors the remote one.
+// It returns the local multichan and a reader of it.
+// More readers may be added to the multichan,
+// but it should not be written to.
+// The dial options may be nil.
+//
+// The local multichan is closed when the remote one is,
+// or when the last of its readers is disposed of.
+// It is closed with an error (see multichan.W.CloseWithError)
+// when the context is canceled,
+// or when the connection fails,
+// or when the server sends a frame larger than the limit in dopts.
+func Dial[T any](ctx context.Context, network, addr string, codec multichan.Codec[T], dopts *DialOptions, opts ...multichan.Option) (*multichan.W[T], *multichan.R[T], error) {
+	maxFrame := DefaultMaxFrame
+	if dopts != nil && dopts.MaxFrame > 0 {
+		maxFrame = dopts.MaxFrame
+	}
+
+	w, err := multichan.NewChecked[T](opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	r := w.Reader()
+
+	received := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-w.Done():
+		case <-received:
+		}
+		conn.Close()
+	}()
+	go func() {
+		defer close(received)
+		err := receive(conn, w, codec, maxFrame)
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		w.CloseWithError(err)
+	}()
+
+	return w, r, nil
+}
+
+// receive writes the items arriving on conn to w
+// until the end of the stream,
+// when it returns nil,
+// or until reading fails
+// or a frame is larger than maxFrame.
+func receive[T any](conn net.Conn, w *multichan.W[T], codec multichan.Codec[T], maxFrame int) error {
+	br := bufio.NewReader(conn)
+	var hdr [4]byte
+	for {
+		if _, err := io.ReadFull(br, hdr[:]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		n := binary.BigEndian.Uint32(hdr[:])
+		if n == endOfStream {
+			return nil
+		}
+		if uint64(n) > uint64(maxFrame) {
+			return fmt.Errorf("frame of %d bytes exceeds limit of %d", n, maxFrame)
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return err
+		}
+		val, err := codec.Decode(data)
+		if err != nil {
+			return fmt.Errorf("decoding item: %w", err)
+		}
+		if err := w.Write(val); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package sock
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bobg/multichan"
+)
+
+func TestSock(t *testing.T) {
+	type point struct{ X, Y int }
+
+	cases := []struct {
+		network string
+		codec   multichan.Codec[point]
+	}{
+		{"tcp", multichan.GobCodec[point]{}},
+		{"unix", multichan.JSONCodec[point]{}},
+	}
+	for _, c := range cases {
+		t.Run(c.network, func(t *testing.T) {
+			addr := "127.0.0.1:0"
+			if c.network == "unix" {
+				addr = filepath.Join(t.TempDir(), "sock")
+			}
+			ln, err := net.Listen(c.network, addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			w := multichan.New[point]()
+			served := make(chan error)
+			go func() {
+				served <- Serve(ctx, ln, w, c.codec)
+			}()
+
+			_, r1, err := Dial(ctx, c.network, ln.Addr().String(), c.codec, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			local, r2, err := Dial(ctx, c.network, ln.Addr().String(), c.codec, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for w.NumReaders() < 2 {
+				time.Sleep(time.Millisecond)
+			}
+
+			w.WriteBatch([]point{{1, 2}, {3, 4}})
+			if got, ok := r2.Read(ctx); !ok || got != (point{1, 2}) {
+				t.Errorf("got %v, %v; want {1 2}, true", got, ok)
+			}
+
+			// Disposing of the last reader of a local multichan closes its connection.
+			r2.Dispose()
+			for w.NumReaders() > 1 || !local.Closed() {
+				time.Sleep(time.Millisecond)
+			}
+
+			w.Write(point{5, 6})
+			w.Close()
+			got, err := r1.Drain(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []point{{1, 2}, {3, 4}, {5, 6}}; !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+
+			cancel()
+			if err := <-served; !errors.Is(err, context.Canceled) {
+				t.Errorf("got %v, want %v", err, context.Canceled)
+			}
+		})
+	}
+}
+
+func TestMaxFrame(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write(binary.BigEndian.AppendUint32(nil, 1<<30))
+		io.Copy(io.Discard, conn)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, r, err := Dial(ctx, "tcp", ln.Addr().String(), multichan.JSONCodec[int]{}, &DialOptions{MaxFrame: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.Read(ctx); ok {
+		t.Fatal("got an item")
+	}
+	if ctx.Err() != nil {
+		t.Fatal(ctx.Err())
+	}
+	if r.Err() == nil {
+		t.Error("got no error")
+	}
+}
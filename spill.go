@@ -0,0 +1,236 @@
+package multichan
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// A Codec converts values of type T to and from bytes,
+// so they can be stored outside of memory (see Spill).
+type Codec[T any] interface {
+	Encode(T) ([]byte, error)
+	Decode([]byte) (T, error)
+}
+
+// spillSegmentSize is the size at which a spill file stops growing
+// and a new one is started,
+// so that the disk space of items no longer retained can be reclaimed
+// (by removing whole files)
+// while a slow reader holds on to newer ones.
+const spillSegmentSize = 64 << 20
+
+// A spiller moves the values of a multichan's oldest retained items
+// out of memory and into temporary files
+// (see Spill).
+type spiller[T any] struct {
+	codec    Codec[T]
+	dir      string
+	maxItems int         // 0 means no limit
+	maxBytes int64       // 0 means no limit
+	size     func(T) int // nil unless maxBytes > 0
+
+	items int   // the number of live items whose values are in memory
+	bytes int64 // the total size of those values, if size is set
+	pos   int64 // the stream offset of the next item to consider spilling
+
+	cur *segment // the file being written, or nil
+	err error    // the error that stopped spilling, if any
+}
+
+// A segment is one of a spiller's temporary files.
+type segment struct {
+	f    *os.File
+	buf  *bufio.Writer
+	name string // the file's name, if it remains to be removed
+	size int64  // the number of bytes written, including those still buffered
+	live int    // the number of items whose values are stored here
+}
+
+// A spillRef locates the encoded value of a spilled item.
+type spillRef struct {
+	seg *segment
+	off int64
+	n   int
+}
+
+func (s *spiller[T]) over() bool {
+	return (s.maxItems > 0 && s.items > s.maxItems) || (s.maxBytes > 0 && s.bytes > s.maxBytes)
+}
+
+// stored accounts for a new item whose value is in memory.
+func (s *spiller[T]) stored(val T) {
+	s.items++
+	if s.size != nil {
+		s.bytes += int64(s.size(val))
+	}
+}
+
+// spillOver spills the values of w's oldest in-memory items
+// until the rest are within the limits of w's spiller.
+// A value that cannot be spilled stays in memory.
+// Callers must hold w.mu.
+func (w *W[T]) spillOver() {
+	s := w.spill
+	for s.err == nil && s.over() {
+		i := w.index(s.pos)
+		if i == w.items.len() {
+			return
+		}
+		it := w.items.at(i)
+		s.pos = it.offset + 1
+		if it.dead || it.spill != nil {
+			continue
+		}
+		data, err := s.codec.Encode(it.val)
+		if err != nil {
+			w.log(w.logLevels.Spill, "multichan cannot encode item", slog.Int64("offset", it.offset), slog.String("error", err.Error()))
+			continue
+		}
+		ref, err := s.write(data)
+		if err != nil {
+			s.err = err
+			w.log(w.logLevels.Spill, "multichan cannot spill items", slog.String("error", err.Error()))
+			return
+		}
+		it.spill = ref
+		w.unspilled(it.val)
+		var zero T
+		it.val = zero // allow the garbage collector to reclaim the value
+	}
+}
+
+// unspilled accounts for the removal from memory of the value of an item.
+func (w *W[T]) unspilled(val T) {
+	s := w.spill
+	s.items--
+	if s.size != nil {
+		s.bytes -= int64(s.size(val))
+	}
+}
+
+// forget accounts for the removal of it, which is not dead, from the queue.
+// Callers must hold w.mu.
+func (w *W[T]) forget(it *item[T]) {
+	if it.spill == nil {
+		w.unspilled(it.val)
+		return
+	}
+	w.spill.release(it.spill)
+	it.spill = nil
+}
+
+// value returns the value of it,
+// reading it back from disk if it has been spilled.
+// Callers must hold w.mu.
+func (w *W[T]) value(it *item[T]) T {
+	if it.spill == nil {
+		return it.val
+	}
+	data, err := it.spill.read()
+	if err != nil {
+		panic(fmt.Sprintf("multichan: reading spilled item %d: %s", it.offset, err))
+	}
+	val, err := w.spill.codec.Decode(data)
+	if err != nil {
+		panic(fmt.Sprintf("multichan: decoding spilled item %d: %s", it.offset, err))
+	}
+	return val
+}
+
+// write appends data to s's current segment,
+// starting a new one if needed,
+// and returns its location.
+func (s *spiller[T]) write(data []byte) (*spillRef, error) {
+	if s.cur == nil || s.cur.size >= spillSegmentSize {
+		if s.cur != nil && s.cur.live == 0 {
+			s.cur.close()
+		}
+		seg, err := newSegment(s.dir)
+		if err != nil {
+			return nil, err
+		}
+		s.cur = seg
+	}
+	if _, err := s.cur.buf.Write(data); err != nil {
+		return nil, err
+	}
+	ref := &spillRef{seg: s.cur, off: s.cur.size, n: len(data)}
+	s.cur.size += int64(len(data))
+	s.cur.live++
+	return ref, nil
+}
+
+// release frees the storage of a spilled value that is no longer needed.
+// A segment is closed and removed when none of its values is needed,
+// unless it is the current one,
+// which is instead emptied for reuse.
+func (s *spiller[T]) release(ref *spillRef) {
+	seg := ref.seg
+	seg.live--
+	if seg.live > 0 {
+		return
+	}
+	if seg != s.cur {
+		seg.close()
+		return
+	}
+	if err := seg.buf.Flush(); err != nil {
+		return
+	}
+	if err := seg.f.Truncate(0); err != nil {
+		return
+	}
+	if _, err := seg.f.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+	seg.size = 0
+}
+
+// retire stops writing to s's current segment,
+// removing it if none of its values is needed.
+// This is for when the multichan closes.
+func (s *spiller[T]) retire() {
+	if s.cur != nil && s.cur.live == 0 {
+		s.cur.close()
+	}
+	s.cur = nil
+}
+
+func newSegment(dir string) (*segment, error) {
+	f, err := os.CreateTemp(dir, "multichan-spill-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating spill file: %w", err)
+	}
+	seg := &segment{f: f, buf: bufio.NewWriter(f)}
+	if err := os.Remove(f.Name()); err != nil {
+		// Some systems cannot remove an open file.
+		// Try again on closing it.
+		seg.name = f.Name()
+	}
+	return seg, nil
+}
+
+// read returns the encoded value at ref.
+func (ref *spillRef) read() ([]byte, error) {
+	seg := ref.seg
+	if ref.off+int64(ref.n) > seg.size-int64(seg.buf.Buffered()) {
+		if err := seg.buf.Flush(); err != nil {
+			return nil, err
+		}
+	}
+	data := make([]byte, ref.n)
+	if _, err := seg.f.ReadAt(data, ref.off); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (seg *segment) close() {
+	seg.f.Close()
+	if seg.name != "" {
+		os.Remove(seg.name)
+	}
+}
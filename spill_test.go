@@ -0,0 +1,124 @@
+package multichan
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+type intCodec struct{}
+
+func (intCodec) Encode(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, errors.New("negative")
+	}
+	return []byte(strconv.Itoa(n)), nil
+}
+
+func (intCodec) Decode(b []byte) (int, error) {
+	return strconv.Atoi(string(b))
+}
+
+func TestSpill(t *testing.T) {
+	w := New[int](Spill[int](10, intCodec{}), SpillDir(t.TempDir()))
+	fast := w.Reader()
+	slow := w.Reader()
+
+	var want []int
+	for i := range 1000 {
+		w.Write(i)
+		want = append(want, i)
+		fast.NBRead()
+	}
+	if w.spill.items != 10 {
+		t.Errorf("got %d items in memory, want 10", w.spill.items)
+	}
+	if w.spill.cur == nil || w.spill.cur.live != 990 {
+		t.Fatal("values not spilled")
+	}
+
+	for range 500 {
+		slow.NBRead()
+	}
+	if w.spill.cur.live != 490 {
+		t.Errorf("got %d spilled values, want 490", w.spill.cur.live)
+	}
+
+	w.Close()
+	got, err := slow.Drain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want[500:]) {
+		t.Errorf("got %v, want %v", got, want[500:])
+	}
+	if w.spill.items != 0 {
+		t.Errorf("got %d items in memory, want 0", w.spill.items)
+	}
+}
+
+func TestSpillReuse(t *testing.T) {
+	w := New[int](Spill[int](1, intCodec{}))
+	r := w.Reader()
+
+	w.WriteBatch([]int{1, 2, 3})
+	seg := w.spill.cur
+	if seg == nil || seg.size == 0 {
+		t.Fatal("values not spilled")
+	}
+	if val, _ := r.Peek(); val != 1 {
+		t.Errorf("got %d, want 1", val)
+	}
+	for range 3 {
+		r.NBRead()
+	}
+	if seg.live != 0 || seg.size != 0 {
+		t.Errorf("got %d live values in %d bytes, want 0 in 0", seg.live, seg.size)
+	}
+	if fi, err := seg.f.Stat(); err != nil {
+		t.Fatal(err)
+	} else if fi.Size() != 0 {
+		t.Errorf("got file size %d, want 0", fi.Size())
+	}
+
+	w.Write(4)
+	w.Write(5)
+	if val, _ := r.NBRead(); val != 4 {
+		t.Errorf("got %d, want 4", val)
+	}
+	if w.spill.cur != seg {
+		t.Error("spill file not reused")
+	}
+
+	w.Close()
+	if _, err := seg.f.Stat(); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("got %v, want %v", err, os.ErrClosed)
+	}
+}
+
+func TestSpillBytes(t *testing.T) {
+	size := func(n int) int { return max(n, -n) }
+	w := New[int](SpillBytes(10, size, intCodec{}))
+	r := w.Reader()
+
+	w.WriteBatch([]int{5, -1, 4, 3, 2, 1})
+
+	// The encoding of -1 fails, so it stays in memory with 3, 2, and 1.
+	if w.spill.bytes != 7 {
+		t.Errorf("got %d bytes in memory, want 7", w.spill.bytes)
+	}
+	if w.spill.cur.live != 2 {
+		t.Errorf("got %d spilled values, want 2", w.spill.cur.live)
+	}
+
+	w.Close()
+	got, err := r.Drain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{5, -1, 4, 3, 2, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
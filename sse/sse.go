@@ -0,0 +1,143 @@
+// Package sse streams the items of a multichan to HTTP clients as server-sent events.
+package sse
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bobg/multichan"
+)
+
+// Event is a server-sent event.
+// Its ID is the stream offset of the item it encodes
+// and is supplied by the Handler.
+type Event struct {
+	// Type is the event type,
+	// which a browser uses to dispatch the event
+	// (see EventSource.addEventListener).
+	// If it is empty,
+	// the event has the default type, "message".
+	// Any CR or LF characters in it are removed.
+	Type string
+
+	// Data is the event's data.
+	// It may contain line breaks (CRLF, CR, or LF).
+	Data string
+}
+
+// Handler is an http.Handler
+// that streams the items of a multichan to each client as server-sent events.
+// Each request gets its own reader,
+// which is disposed of when the client disconnects.
+// The response ends when the multichan is closed
+// and the reader has consumed the last item.
+//
+// The ID of each event is the stream offset of its item (see multichan.R.ReadOffset).
+// A client that reconnects with the Last-Event-ID header
+// (as browsers do automatically)
+// resumes with the item after that one,
+// if the multichan still retains it (see multichan.Retain),
+// and otherwise with the oldest item the multichan retains.
+//
+// Create one with NewHandler.
+type Handler[T any] struct {
+	w      *multichan.W[T]
+	encode func(T) (Event, error)
+	opts   []multichan.ReaderOption
+}
+
+var _ http.Handler = (*Handler[any])(nil)
+
+// NewHandler produces a new Handler streaming the items of w,
+// each converted to an event with the given function,
+// to readers created with the given options.
+// An item for which encode returns an error is skipped.
+func NewHandler[T any](w *multichan.W[T], encode func(T) (Event, error), opts ...multichan.ReaderOption) *Handler[T] {
+	return &Handler[T]{w: w, encode: encode, opts: opts}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler[T]) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	r, err := h.reader(req)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer r.Dispose()
+
+	rc := http.NewResponseController(rw)
+	hdr := rw.Header()
+	hdr.Set("Content-Type", "text/event-stream")
+	hdr.Set("Cache-Control", "no-cache")
+	rw.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	ctx := req.Context()
+	for {
+		val, offset, ok := r.ReadOffset(ctx)
+		if !ok {
+			return
+		}
+		ev, err := h.encode(val)
+		if err != nil {
+			continue
+		}
+		if _, err := rw.Write(ev.marshal(offset)); err != nil {
+			return
+		}
+		if r.Pending() > 0 {
+			// Send more events before flushing.
+			continue
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// reader creates the reader for a request,
+// resuming after the event given in its Last-Event-ID header, if any.
+func (h *Handler[T]) reader(req *http.Request) (*multichan.R[T], error) {
+	lastID := req.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		return h.w.Reader(h.opts...), nil
+	}
+	offset, err := strconv.ParseInt(lastID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Last-Event-ID: %w", err)
+	}
+	r, err := h.w.ReaderAt(offset+1, h.opts...)
+	if errors.Is(err, multichan.ErrOffsetRange) {
+		opts := append(h.opts[:len(h.opts):len(h.opts)], multichan.FromEarliest())
+		return h.w.Reader(opts...), nil
+	}
+	return r, err
+}
+
+// lineBreaks removes the characters that end a line in an event stream.
+var lineBreaks = strings.NewReplacer("\r", "", "\n", "")
+
+// marshal produces the wire form of ev with the given ID.
+// Each line of ev.Data,
+// ended by CRLF, CR, or LF as in the event stream format,
+// becomes a separate data field.
+func (ev Event) marshal(id int64) []byte {
+	buf := new(strings.Builder)
+	fmt.Fprintf(buf, "id: %d\n", id)
+	if ev.Type != "" {
+		// A line break would end the field and let the rest inject others.
+		fmt.Fprintf(buf, "event: %s\n", lineBreaks.Replace(ev.Type))
+	}
+	data := strings.ReplaceAll(ev.Data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(buf, "data: %s\n", line)
+	}
+	buf.WriteString("\n")
+	return []byte(buf.String())
+}
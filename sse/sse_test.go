@@ -0,0 +1,119 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bobg/multichan"
+)
+
+func TestHandler(t *testing.T) {
+	w := multichan.New[int](multichan.Retain(10))
+	h := NewHandler(w, func(n int) (Event, error) {
+		ev := Event{Data: strconv.Itoa(n)}
+		if n%2 == 0 {
+			ev.Type = "even"
+			ev.Data += "\nline 2"
+		}
+		return ev, nil
+	})
+	s := httptest.NewServer(h)
+	defer s.Close()
+
+	get := func(ctx context.Context, lastID string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequestWithContext(ctx, "GET", s.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lastID != "" {
+			req.Header.Set("Last-Event-ID", lastID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	waitForReaders := func(n int) {
+		for w.NumReaders() != n {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	check := func(body io.Reader, want ...string) {
+		t.Helper()
+		var got []string
+		sc := bufio.NewScanner(body)
+		for len(got) < len(want) && sc.Scan() {
+			got = append(got, sc.Text())
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+	}
+
+	w.WriteBatch([]int{1, 2, 3})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	resp := get(ctx, "0")
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("got content type %s", ct)
+	}
+	waitForReaders(1)
+	w.Write(4)
+	check(
+		resp.Body,
+		"id: 1", "event: even", "data: 2", "data: line 2", "",
+		"id: 2", "data: 3", "",
+		"id: 3", "event: even", "data: 4", "data: line 2", "",
+	)
+	cancel()
+	resp.Body.Close()
+	waitForReaders(0)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	resp = get(ctx, "")
+	waitForReaders(1)
+	w.Write(5)
+	check(resp.Body, "id: 4", "data: 5", "")
+	cancel()
+	resp.Body.Close()
+	waitForReaders(0)
+
+	resp = get(context.Background(), "x")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("got status %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	w.Close()
+	resp = get(context.Background(), "3")
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "id: 4\ndata: 5\n\n"; string(body) != want {
+		t.Errorf("got %q, want %q", body, want)
+	}
+}
+
+func TestMarshalLineBreaks(t *testing.T) {
+	ev := Event{
+		Type: "x\r\nevent: y",
+		Data: "a\rb\r\nc\nd",
+	}
+	got := string(ev.marshal(7))
+	want := "id: 7\nevent: xevent: y\ndata: a\ndata: b\ndata: c\ndata: d\n\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,131 @@
+package multichan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Stats is a snapshot of a multichan's state.
+// See W.Stats.
+type Stats struct {
+	// Len is the number of items the multichan currently retains (see W.Len).
+	Len int
+
+	// Written is the total number of items ever written to the multichan.
+	Written int64
+
+	// Trimmed is the total number of items removed from the multichan's queue,
+	// whether consumed by every reader or discarded for another reason.
+	Trimmed int64
+
+	// Dropped is the total number of items discarded before every reader consumed them,
+	// by an overflow policy (see Capacity), expiry (see TTL),
+	// or rejection (see MaxAttempts).
+	// See DeadLetter.
+	Dropped int64
+
+	// Readers describes each of the multichan's readers (see W.ReaderInfo).
+	// Its length is the number of readers.
+	Readers []ReaderInfo
+}
+
+// MaxLag returns the greatest Lag among s.Readers,
+// or 0 if there are none.
+func (s Stats) MaxLag() int {
+	var maxLag int
+	for _, info := range s.Readers {
+		maxLag = max(maxLag, info.Lag)
+	}
+	return maxLag
+}
+
+// Stats returns a snapshot of w's state,
+// for monitoring.
+func (w *W[T]) Stats() Stats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.expire()
+	return w.stats()
+}
+
+// stats returns a snapshot of w's state.
+// Callers must hold w.mu.
+func (w *W[T]) stats() Stats {
+	return Stats{
+		Len:     w.items.len() - w.dead,
+		Written: w.next,
+		Trimmed: w.trimmed,
+		Dropped: w.dropped,
+		Readers: w.readerInfo(),
+	}
+}
+
+// DebugString returns a human-readable description of w's internal state,
+// for debugging stuck consumers and the like.
+// Its format is not stable.
+func (w *W[T]) DebugString() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	buf := new(strings.Builder)
+	fmt.Fprintf(buf, "multichan: offsets [%d, %d), %d items retained", w.offset, w.next, w.items.len()-w.dead)
+	if w.dead > 0 {
+		fmt.Fprintf(buf, " (plus %d dead)", w.dead)
+	}
+	if w.capacity > 0 {
+		fmt.Fprintf(buf, ", capacity %d", w.capacity)
+	}
+	if w.closed {
+		buf.WriteString(", closed")
+		if w.err != nil {
+			fmt.Fprintf(buf, " (%s)", w.err)
+		}
+	}
+	fmt.Fprintf(buf, ", %d readers", len(w.readers))
+
+	infos := w.readerInfo()
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Pos < infos[j].Pos })
+	for _, info := range infos {
+		fmt.Fprintf(buf, "\n  reader %q: pos %d, lag %d", info.Name, info.Pos, info.Lag)
+	}
+
+	return buf.String()
+}
+
+// expvarMu serializes the checking and publishing of names for the Expvar option.
+var expvarMu sync.Mutex
+
+// expvarStats is the source of the statistics a multichan publishes for the Expvar option.
+// An expvar variable can never be unpublished,
+// so expvarStats refers to its multichan only until the multichan is closed,
+// after which it reports the multichan's final statistics.
+type expvarStats struct {
+	mu    sync.Mutex
+	stats func() Stats // nil once the multichan is closed
+	final Stats
+}
+
+func (v *expvarStats) value() any {
+	v.mu.Lock()
+	stats, final := v.stats, v.final
+	v.mu.Unlock()
+
+	// Not called with v.mu held,
+	// since closing the multichan acquires v.mu while holding the multichan's lock.
+	if stats == nil {
+		return final
+	}
+	return stats()
+}
+
+// close records the final statistics of the multichan
+// and drops the reference to it.
+func (v *expvarStats) close(final Stats) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.stats, v.final = nil, final
+}
@@ -0,0 +1,128 @@
+package multichan
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"reflect"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	w := New[int](Capacity(3, DropOldest))
+	r1 := w.Reader()
+	w.WriteBatch([]int{1, 2, 3, 4})
+	r2 := w.Reader()
+	w.Write(5)
+	r1.Read(nil)
+
+	s := w.Stats()
+	if s.Len != 2 {
+		t.Errorf("got Len %d, want 2", s.Len)
+	}
+	if s.Written != 5 {
+		t.Errorf("got Written %d, want 5", s.Written)
+	}
+	if s.Trimmed != 3 {
+		t.Errorf("got Trimmed %d, want 3", s.Trimmed)
+	}
+	if s.Dropped != 2 {
+		t.Errorf("got Dropped %d, want 2", s.Dropped)
+	}
+	if len(s.Readers) != 2 {
+		t.Errorf("got %d readers, want 2", len(s.Readers))
+	}
+	if lag := s.MaxLag(); lag != 2 {
+		t.Errorf("got max lag %d, want 2", lag)
+	}
+
+	r1.Dispose()
+	r2.Dispose()
+}
+
+func TestExpvar(t *testing.T) {
+	w := New[int](Expvar("multichan_test"))
+	r := w.Reader()
+	defer r.Dispose()
+	w.Write(1)
+
+	v := expvar.Get("multichan_test")
+	if v == nil {
+		t.Fatal("not published")
+	}
+	var s Stats
+	if err := json.Unmarshal([]byte(v.String()), &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Written != 1 || s.Len != 1 || len(s.Readers) != 1 {
+		t.Errorf("got %+v, want 1 item written and retained, with 1 reader", s)
+	}
+
+	if _, err := NewChecked[int](Expvar("multichan_test")); !errors.Is(err, ErrExpvarName) {
+		t.Errorf("got error %v for a duplicate name, want %v", err, ErrExpvarName)
+	}
+
+	w.Write(2)
+	w.Close()
+	if w.expvar.stats != nil {
+		t.Error("published variable still refers to the closed multichan")
+	}
+	s = Stats{}
+	if err := json.Unmarshal([]byte(v.String()), &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Written != 2 || s.Len != 2 {
+		t.Errorf("got %+v after close, want 2 items written and retained", s)
+	}
+}
+
+func TestShardedExpvar(t *testing.T) {
+	NewSharded[int](2, Expvar("multichan_sharded_test"))
+	for _, name := range []string{"multichan_sharded_test.0", "multichan_sharded_test.1"} {
+		if expvar.Get(name) == nil {
+			t.Errorf("%s not published", name)
+		}
+	}
+}
+
+func TestOnLag(t *testing.T) {
+	type event struct {
+		lag    int
+		behind bool
+	}
+	var got []event
+	w := New[int](OnLag(3, 1, func(info ReaderInfo, behind bool) {
+		got = append(got, event{info.Lag, behind})
+	}))
+	r := w.Reader()
+	defer r.Dispose()
+
+	w.WriteBatch([]int{1, 2, 3, 4})
+	r.ReadN(nil, 2)
+	r.Read(nil)
+	w.Write(5)
+
+	want := []event{{3, true}, {1, false}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestDebugString(t *testing.T) {
+	w := New[int](Capacity(10, Block))
+	r1 := w.Reader(Name("r1"))
+	w.WriteBatch([]int{1, 2, 3})
+	r2 := w.Reader(Name("r2"))
+	w.Write(4)
+	r1.Read(nil)
+	w.CloseWithError(errors.New("boom"))
+	defer r1.Dispose()
+	defer r2.Dispose()
+
+	const want = `multichan: offsets [1, 4), 3 items retained, capacity 10, closed (boom), 2 readers
+  reader "r1": pos 1, lag 3
+  reader "r2": pos 3, lag 1`
+	if got := w.DebugString(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
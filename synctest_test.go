@@ -0,0 +1,90 @@
+//go:build go1.25
+
+package multichan
+
+import (
+	"context"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+// These tests check that time-based and blocking behavior
+// works deterministically in a synctest bubble,
+// where time advances only when every goroutine is blocked.
+
+func TestSynctestRead(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		w := New[int]()
+		r := w.Reader()
+
+		ctx, cancel := context.WithTimeout(t.Context(), time.Minute)
+		defer cancel()
+
+		go func() {
+			time.Sleep(30 * time.Second)
+			w.Write(1)
+		}()
+
+		start := time.Now()
+		if val, ok := r.Read(ctx); !ok || val != 1 {
+			t.Fatalf("got %d, %v; want 1, true", val, ok)
+		}
+		if d := time.Since(start); d != 30*time.Second {
+			t.Errorf("read took %v, want 30s", d)
+		}
+
+		if _, ok := r.Read(ctx); ok {
+			t.Fatal("got an item, want timeout")
+		}
+		if d := time.Since(start); d != time.Minute {
+			t.Errorf("timed out after %v, want 1m", d)
+		}
+	})
+}
+
+func TestSynctestTTL(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		w := New[int](TTL(time.Minute))
+		r := w.Reader()
+
+		w.Write(1)
+		time.Sleep(30 * time.Second)
+		w.Write(2)
+		time.Sleep(45 * time.Second)
+		synctest.Wait()
+
+		if n := r.Pending(); n != 1 {
+			t.Fatalf("got %d pending items, want 1", n)
+		}
+		if val, ok := r.NBRead(); !ok || val != 2 {
+			t.Errorf("got %d, %v; want 2, true", val, ok)
+		}
+	})
+}
+
+func TestSynctestTicker(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+
+		start := time.Now()
+		w := NewTicker(ctx, time.Second)
+		r := w.Reader()
+
+		for i := 1; i <= 3; i++ {
+			tick, ok := r.Read(ctx)
+			if !ok {
+				t.Fatal("ticker closed early")
+			}
+			if want := start.Add(time.Duration(i) * time.Second); !tick.Equal(want) {
+				t.Errorf("got tick at %v, want %v", tick, want)
+			}
+		}
+
+		cancel()
+		synctest.Wait()
+		if !w.Closed() {
+			t.Error("ticker not closed")
+		}
+	})
+}
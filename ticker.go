@@ -0,0 +1,102 @@
+package multichan
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// NewTicker produces a new multichan
+// to which the current time is written every d,
+// like the channel of a time.Ticker,
+// but with any number of readers,
+// each consuming ticks at its own pace.
+//
+// The multichan is created with Capacity(1, DropOldest),
+// followed by opts,
+// so a slow reader does not accumulate stale ticks:
+// it sees only the latest one.
+// Time is measured with the multichan's clock (see UseClock).
+//
+// Ticks are written until the context is canceled,
+// at which point the multichan is closed with the context's error,
+// or until the multichan is closed.
+// NewTicker panics if d is not positive.
+func NewTicker(ctx context.Context, d time.Duration, opts ...Option) *W[time.Time] {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+
+	w := New[time.Time](append([]Option{Capacity(1, DropOldest)}, opts...)...)
+
+	var (
+		mu    sync.Mutex // protects timer, which tick uses before AfterFunc returns it
+		timer Timer
+	)
+	tick := func() {
+		if ctx.Err() != nil || w.WriteContext(ctx, w.clock.Now()) != nil {
+			return
+		}
+		mu.Lock()
+		timer.Reset(d)
+		mu.Unlock()
+	}
+
+	mu.Lock()
+	timer = w.clock.AfterFunc(d, tick)
+	mu.Unlock()
+
+	context.AfterFunc(ctx, func() {
+		mu.Lock()
+		timer.Stop()
+		mu.Unlock()
+		w.CloseWithError(ctx.Err())
+	})
+
+	return w
+}
+
+// NewTimer produces a new multichan
+// to which the current time is written once, after d,
+// like the channel of a time.Timer,
+// but with any number of readers.
+// The multichan is then closed.
+//
+// The multichan is created with Sticky(),
+// followed by opts,
+// so readers added after the time is written still see it.
+// Time is measured with the multichan's clock (see UseClock).
+//
+// If the context is canceled before d elapses,
+// the multichan is closed with the context's error
+// and nothing is written.
+func NewTimer(ctx context.Context, d time.Duration, opts ...Option) *W[time.Time] {
+	w := New[time.Time](append([]Option{Sticky()}, opts...)...)
+
+	var (
+		mu   sync.Mutex // protects stop, which fire uses before AfterFunc returns it
+		stop func() bool
+	)
+	fire := func() {
+		mu.Lock()
+		ok := stop()
+		mu.Unlock()
+		if !ok {
+			// The context was canceled first.
+			return
+		}
+		w.Write(w.clock.Now())
+		w.Close()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	timer := w.clock.AfterFunc(d, fire)
+	stop = context.AfterFunc(ctx, func() {
+		timer.Stop()
+		w.CloseWithError(ctx.Err())
+	})
+
+	return w
+}
@@ -0,0 +1,78 @@
+package multichan
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTicker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w := NewTicker(ctx, time.Millisecond)
+	fast, slow := w.Reader(), w.Reader()
+
+	var last time.Time
+	for range 5 {
+		tick, ok := fast.Read(ctx)
+		if !ok {
+			t.Fatal("ticker closed early")
+		}
+		if !tick.After(last) {
+			t.Errorf("tick %v not after %v", tick, last)
+		}
+		last = tick
+	}
+
+	// The slow reader sees only the latest tick.
+	if n := slow.Pending(); n > 1 {
+		t.Errorf("got %d pending ticks, want at most 1", n)
+	}
+
+	cancel()
+	for {
+		if _, ok := fast.Read(nil); !ok {
+			break
+		}
+	}
+	if err := fast.Err(); err != context.Canceled {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestTimer(t *testing.T) {
+	ctx := context.Background()
+
+	start := time.Now()
+	w := NewTimer(ctx, 10*time.Millisecond)
+	r := w.Reader()
+	fired, ok := r.Read(ctx)
+	if !ok {
+		t.Fatal("timer closed without firing")
+	}
+	if fired.Sub(start) < 10*time.Millisecond {
+		t.Errorf("timer fired after %v, want at least 10ms", fired.Sub(start))
+	}
+	if _, ok := r.Read(ctx); ok {
+		t.Error("got a second item, want end of stream")
+	}
+
+	// A late reader still sees the time.
+	if got, ok := w.Reader().Read(ctx); !ok || !got.Equal(fired) {
+		t.Errorf("got %v, %v; want %v, true", got, ok, fired)
+	}
+}
+
+func TestTimerCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := NewTimer(ctx, time.Hour)
+	r := w.Reader()
+	cancel()
+	if _, ok := r.Read(nil); ok {
+		t.Error("got an item, want end of stream")
+	}
+	if err := r.Err(); err != context.Canceled {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,114 @@
+package multichan
+
+import (
+	"slices"
+	"sync"
+)
+
+// topics is a collection of multichans identified by keys,
+// each created when it first gets a reader
+// and closed and removed when its last reader goes away.
+// It is the basis of Broker and Demux.
+type topics[K comparable, T any] struct {
+	mu     sync.Mutex
+	opts   []Option
+	ws     map[K]*W[T]
+	closed bool
+}
+
+func newTopics[K comparable, T any](opts []Option) *topics[K, T] {
+	// Each multichan would publish under the same name (see Expvar).
+	noExpvar := func(c *config) { c.expvar = "" }
+	return &topics[K, T]{
+		opts: append(slices.Clip(opts), noExpvar),
+		ws:   make(map[K]*W[T]),
+	}
+}
+
+// publish writes val to the multichan with the given key,
+// discarding it if there is none.
+func (t *topics[K, T]) publish(key K, val T) error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return ErrClosed
+	}
+	w := t.ws[key]
+	t.mu.Unlock()
+
+	if w == nil {
+		return nil
+	}
+	err := w.Write(val)
+	if err == ErrClosed {
+		// Perhaps w lost its last reader since it was looked up.
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if !t.closed {
+			return nil
+		}
+	}
+	return err
+}
+
+// subscribe adds a reader to the multichan with the given key,
+// creating it if necessary.
+func (t *topics[K, T]) subscribe(key K, opts []ReaderOption) *R[T] {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		w := New[T]()
+		w.Close()
+		return w.Reader(opts...)
+	}
+
+	w := t.ws[key]
+	if w == nil {
+		w = New[T](t.opts...)
+		t.ws[key] = w
+		defer func() { go t.reap(key, w) }()
+	}
+	return w.Reader(opts...)
+}
+
+// reap waits for w, the multichan with the given key, to lose its last reader,
+// then closes and removes it.
+func (t *topics[K, T]) reap(key K, w *W[T]) {
+	for {
+		<-w.Done()
+
+		t.mu.Lock()
+		if w.NumReaders() == 0 {
+			// No new reader can be added to w while t is locked.
+			if t.ws[key] == w {
+				delete(t.ws, key)
+			}
+			w.Close()
+			t.mu.Unlock()
+			return
+		}
+		t.mu.Unlock()
+	}
+}
+
+func (t *topics[K, T]) keys() []K {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	keys := make([]K, 0, len(t.ws))
+	for key := range t.ws {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (t *topics[K, T]) close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.closed = true
+	for _, w := range t.ws {
+		w.Close()
+	}
+}
@@ -0,0 +1,102 @@
+package multichan
+
+import (
+	"container/heap"
+	"time"
+)
+
+// An expiry records when the item at a given stream offset expires (see TTL).
+type expiry struct {
+	at     time.Time
+	offset int64
+}
+
+// expiryHeap is a min-heap of expiries, implementing heap.Interface.
+type expiryHeap []expiry
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h expiryHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *expiryHeap) Push(x any) {
+	*h = append(*h, x.(expiry))
+}
+
+func (h *expiryHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// setExpiry records that the item at the given offset expires at the given time,
+// and arranges for the expiry to happen then.
+// Callers must hold w.mu.
+func (w *W[T]) setExpiry(offset int64, at time.Time) {
+	heap.Push(&w.expiries, expiry{at: at, offset: offset})
+	if w.expiries[0].offset == offset {
+		w.schedule()
+	}
+}
+
+// schedule sets w.timer to fire at the earliest pending expiry.
+// Callers must hold w.mu.
+func (w *W[T]) schedule() {
+	if len(w.expiries) == 0 {
+		return
+	}
+	d := w.expiries[0].at.Sub(w.clock.Now())
+	if w.timer == nil {
+		w.timer = w.clock.AfterFunc(d, w.onTimer)
+	} else {
+		w.timer.Reset(d)
+	}
+}
+
+// onTimer removes expired items when w.timer fires,
+// waking any writer waiting for room in the queue.
+func (w *W[T]) onTimer() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.expire() {
+		w.trim()
+		w.broadcast()
+	}
+	w.schedule()
+}
+
+// expire kills items whose expiration time has passed.
+// It reports whether it killed any.
+// Callers must hold w.mu.
+func (w *W[T]) expire() bool {
+	if len(w.expiries) == 0 {
+		return false
+	}
+
+	var (
+		now    = w.clock.Now()
+		minpos = w.minReaderPos()
+		killed bool
+	)
+	for len(w.expiries) > 0 && !w.expiries[0].at.After(now) {
+		e := heap.Pop(&w.expiries).(expiry)
+		if i := w.index(e.offset); i < w.items.len() && w.items.at(i).offset == e.offset && !w.items.at(i).dead {
+			if e.offset >= minpos && w.items.at(i).err == nil {
+				val := w.value(w.items.at(i))
+				w.sendDeadLetter(val, DroppedExpired)
+				if w.onDrop != nil {
+					for _, r := range w.readers {
+						if _, ok := r.taken[e.offset]; !ok && r.pos <= e.offset {
+							w.dropFor(r, val, DroppedExpired)
+						}
+					}
+				}
+			}
+			w.kill(i)
+			killed = true
+		}
+	}
+	return killed
+}
@@ -0,0 +1,79 @@
+package multichan
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTTL(t *testing.T) {
+	clock := newTestClock()
+	w := New[int](TTL(20*time.Millisecond), UseClock(clock))
+	r := w.Reader()
+
+	w.WriteBatch([]int{1, 2})
+	w.WriteWithTTL(3, 0)
+	clock.advance(40 * time.Millisecond)
+	w.Write(4)
+	w.Close()
+
+	got, err := r.Drain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []int{3, 4}) {
+		t.Errorf("got %v, want [3, 4]", got)
+	}
+}
+
+func TestTTLMakesRoom(t *testing.T) {
+	clock := newTestClock()
+	w := New[int](Capacity(1, Block), TTL(20*time.Millisecond), UseClock(clock))
+	r := w.Reader()
+
+	w.Write(1)
+
+	// This blocks until item 1 expires.
+	errch := make(chan error)
+	go func() { errch <- w.Write(2) }()
+	clock.advance(20 * time.Millisecond)
+	if err := <-errch; err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := r.NBRead()
+	if !ok {
+		t.Fatal("unexpected failure from NBRead")
+	}
+	if got != 2 {
+		t.Errorf("got %d, want 2", got)
+	}
+}
+
+func TestTTLDeadLetter(t *testing.T) {
+	var (
+		clock = newTestClock()
+		got   []int
+	)
+	w := New[int](TTL(10*time.Millisecond), UseClock(clock), DeadLetter(func(val int, reason DropReason) {
+		if reason != DroppedExpired {
+			t.Errorf("got reason %v, want %v", reason, DroppedExpired)
+		}
+		got = append(got, val)
+	}))
+
+	w.Write(1) // no readers, so this is not dead-lettered
+	r := w.Reader()
+	w.Write(2)
+
+	clock.advance(20 * time.Millisecond)
+	if _, ok := r.NBRead(); ok {
+		t.Error("unexpected success from NBRead")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !reflect.DeepEqual(got, []int{2}) {
+		t.Errorf("got %v, want [2]", got)
+	}
+}
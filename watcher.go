@@ -0,0 +1,67 @@
+package multichan
+
+import "sync"
+
+// Watcher pairs a multichan of updates, of type T,
+// with a snapshot of the state they apply to, of type S,
+// so that a new subscriber gets the current state
+// followed by every update after it,
+// with no gap and no duplication
+// (as with a Kubernetes informer).
+//
+// The producer changes its state only inside Watcher.Update,
+// which also writes the item describing the change.
+// The snapshot function runs inside Watcher.Watch,
+// never concurrently with an Update.
+//
+// Create one with NewWatcher.
+type Watcher[S, T any] struct {
+	mu       sync.Mutex // serializes updates and snapshots
+	w        *W[T]
+	snapshot func() S
+}
+
+// NewWatcher produces a new Watcher
+// that takes snapshots with the given function
+// and writes updates to a multichan created with New[T](opts...).
+func NewWatcher[S, T any](snapshot func() S, opts ...Option) *Watcher[S, T] {
+	return &Watcher[S, T]{
+		w:        New[T](opts...),
+		snapshot: snapshot,
+	}
+}
+
+// Update calls f,
+// which should change the producer's state
+// and return the item describing the change,
+// then writes the item to the multichan (see W.Write).
+// No snapshot is taken in between.
+func (wa *Watcher[S, T]) Update(f func() T) error {
+	wa.mu.Lock()
+	defer wa.mu.Unlock()
+	return wa.w.Write(f())
+}
+
+// Watch takes a snapshot of the producer's state
+// and adds a new reader to the multichan,
+// created with the given options,
+// which reads the updates made after the snapshot.
+// Options that position the reader elsewhere,
+// such as FromEarliest,
+// defeat this.
+func (wa *Watcher[S, T]) Watch(opts ...ReaderOption) (S, *R[T]) {
+	wa.mu.Lock()
+	defer wa.mu.Unlock()
+	return wa.snapshot(), wa.w.Reader(opts...)
+}
+
+// Close closes the multichan of updates (see W.Close).
+func (wa *Watcher[S, T]) Close() {
+	wa.w.Close()
+}
+
+// CloseWithError closes the multichan of updates with the given error
+// (see W.CloseWithError).
+func (wa *Watcher[S, T]) CloseWithError(err error) {
+	wa.w.CloseWithError(err)
+}
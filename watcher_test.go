@@ -0,0 +1,53 @@
+package multichan
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestWatcher(t *testing.T) {
+	// The state is a counter,
+	// and each update is its new value.
+	var counter int
+	wa := NewWatcher[int, int](func() int { return counter })
+
+	const n = 1000
+
+	go func() {
+		for i := 0; i < n; i++ {
+			wa.Update(func() int {
+				counter++
+				return counter
+			})
+		}
+		wa.Close()
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			snap, r := wa.Watch()
+			defer r.Dispose()
+
+			want := snap + 1
+			for {
+				got, ok := r.Read(nil)
+				if !ok {
+					break
+				}
+				if got != want {
+					t.Errorf("got update %d after snapshot %d, want %d", got, snap, want)
+					return
+				}
+				want++
+			}
+			if want != n+1 {
+				t.Errorf("stream ended after update %d, want %d", want-1, n)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,10 @@
+module github.com/bobg/multichan/ws
+
+go 1.23
+
+require (
+	github.com/bobg/multichan v0.0.0-00010101000000-000000000000
+	github.com/coder/websocket v1.8.15
+)
+
+replace github.com/bobg/multichan => ../
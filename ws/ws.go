@@ -0,0 +1,320 @@
+// Package ws serves multichans over WebSocket connections
+// and mirrors them in remote processes.
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/bobg/multichan"
+)
+
+// message is the JSON form of an item sent over a WebSocket connection.
+type message[T any] struct {
+	Offset int64 `json:"offset"`
+	Val    T     `json:"val"`
+}
+
+// Handler is an http.Handler
+// that streams the items of a multichan to WebSocket clients,
+// each in a text message containing a JSON object
+// with the stream offset of the item in "offset"
+// and the JSON encoding of the item in "val".
+// Each connection gets its own reader,
+// which is disposed of when the client disconnects.
+// When the multichan is closed and the reader has consumed the last item,
+// the connection is closed with status websocket.StatusNormalClosure.
+//
+// A client can start from a given stream offset
+// with the "offset" query parameter
+// (as Dial does when it reconnects).
+// If the multichan no longer retains the item at that offset (see multichan.Retain),
+// the request fails with status http.StatusGone.
+// The stream offset at which the connection starts
+// is sent in the Multichan-Offset header of the response.
+//
+// Create one with NewHandler.
+type Handler[T any] struct {
+	w      *multichan.W[T]
+	accept *websocket.AcceptOptions
+	opts   []multichan.ReaderOption
+}
+
+var _ http.Handler = (*Handler[any])(nil)
+
+// NewHandler produces a new Handler streaming the items of w
+// to readers created with the given options.
+// Connections are accepted with the given options, which may be nil
+// (see websocket.Accept).
+func NewHandler[T any](w *multichan.W[T], accept *websocket.AcceptOptions, opts ...multichan.ReaderOption) *Handler[T] {
+	return &Handler[T]{w: w, accept: accept, opts: opts}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler[T]) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	r, err := h.reader(req)
+	if errors.Is(err, multichan.ErrOffsetRange) {
+		http.Error(rw, err.Error(), http.StatusGone)
+		return
+	}
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer r.Dispose()
+
+	rw.Header().Set(offsetHeader, strconv.FormatInt(r.Pos(), 10))
+	conn, err := websocket.Accept(rw, req, h.accept)
+	if err != nil {
+		return
+	}
+	defer conn.CloseNow()
+
+	// Clients send nothing,
+	// but reading is needed to respond to control messages,
+	// and to detect the closing of the connection.
+	ctx := conn.CloseRead(req.Context())
+
+	for {
+		val, offset, ok := r.ReadOffset(ctx)
+		if !ok {
+			if ctx.Err() == nil {
+				conn.Close(websocket.StatusNormalClosure, "end of stream")
+			}
+			return
+		}
+		data, err := json.Marshal(message[T]{Offset: offset, Val: val})
+		if err != nil {
+			conn.Close(websocket.StatusInternalError, fmt.Sprintf("encoding item %d", offset))
+			return
+		}
+		if err := conn.Write(ctx, websocket.MessageText, data); err != nil {
+			return
+		}
+	}
+}
+
+// reader creates the reader for a request,
+// starting at the offset in its query, if any.
+func (h *Handler[T]) reader(req *http.Request) (*multichan.R[T], error) {
+	s := req.URL.Query().Get("offset")
+	if s == "" {
+		return h.w.Reader(h.opts...), nil
+	}
+	offset, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid offset: %w", err)
+	}
+	return h.w.ReaderAt(offset, h.opts...)
+}
+
+// offsetHeader is the response header in which Handler sends the starting offset of a connection.
+const offsetHeader = "Multichan-Offset"
+
+// ErrGap is the error with which the multichan produced by Dial is closed
+// when it cannot reconnect without missing items,
+// because the remote multichan no longer retains the next one.
+var ErrGap = errors.New("items missed while reconnecting")
+
+// DefaultReadLimit is the default limit on the size of the messages
+// that Dial accepts from a Handler (see DialOptions).
+const DefaultReadLimit = 16 << 20
+
+// DialOptions holds options for Dial.
+type DialOptions struct {
+	// ReadLimit is the largest message, in bytes,
+	// accepted from the Handler.
+	// A larger message closes the connection and the local multichan with an error,
+	// so that a faulty or malicious server cannot cause a huge allocation.
+	// If it is zero, DefaultReadLimit is used.
+	ReadLimit int64
+}
+
+// Reconnection delays for Dial.
+const (
+	minBackoff = 100 * time.Millisecond
+	maxBackoff = 5 * time.Second
+)
+
+// Dial connects to a Handler at the given URL (with scheme ws or wss)
+// and produces a local multichan,
+// created with multichan.New(opts...),
+// that mirrors the remote one,
+// returning a reader of it.
+// The dial options may be nil.
+//
+// If the connection fails,
+// Dial reconnects,
+// with increasing delays between attempts,
+// and resumes with the item after the last one received.
+// If the remote multichan no longer retains that item,
+// the local one is closed with ErrGap.
+// The local multichan is also closed when the remote one is,
+// or when the reader is disposed of;
+// and with an error (see multichan.W.CloseWithError)
+// when the context is canceled,
+// or when a message cannot be decoded or exceeds the limit in dopts.
+// Only the initial connection's error is returned;
+// later ones are retried.
+func Dial[T any](ctx context.Context, u string, dopts *DialOptions, opts ...multichan.Option) (*multichan.R[T], error) {
+	limit := int64(DefaultReadLimit)
+	if dopts != nil && dopts.ReadLimit > 0 {
+		limit = dopts.ReadLimit
+	}
+
+	w, err := multichan.NewChecked[T](opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, next, err := dial(ctx, u, -1, limit)
+	if err != nil {
+		return nil, err
+	}
+	r := w.Reader()
+
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-w.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	go mirror(ctx, cancel, conn, u, next, limit, w)
+
+	return r, nil
+}
+
+// dial connects to the Handler at u,
+// starting at the given stream offset if it is not negative.
+// It returns the connection
+// and the stream offset at which it starts,
+// or -1 if the Handler does not say.
+func dial(ctx context.Context, u string, offset, limit int64) (*websocket.Conn, int64, error) {
+	if offset >= 0 {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return nil, 0, err
+		}
+		q := parsed.Query()
+		q.Set("offset", strconv.FormatInt(offset, 10))
+		parsed.RawQuery = q.Encode()
+		u = parsed.String()
+	}
+	conn, resp, err := websocket.Dial(ctx, u, nil)
+	if resp != nil && resp.StatusCode == http.StatusGone {
+		return nil, 0, ErrGap
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	conn.SetReadLimit(limit + 1) // see read
+
+	start, err := strconv.ParseInt(resp.Header.Get(offsetHeader), 10, 64)
+	if err != nil {
+		start = offset
+	}
+	return conn, start, nil
+}
+
+// read reads a message from conn,
+// returning errMessageTooBig if it exceeds limit.
+func read(ctx context.Context, conn *websocket.Conn, limit int64) ([]byte, error) {
+	_, rd, err := conn.Reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(rd, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errMessageTooBig
+	}
+	return data, nil
+}
+
+// errMessageTooBig is the error from read for a message exceeding the limit.
+var errMessageTooBig = errors.New("message too big")
+
+// mirror writes the items received from the remote multichan to w,
+// starting at stream offset next (if it is not negative),
+// reconnecting as needed,
+// until the remote multichan or the context is done,
+// then closes w with the error that ended it, if any.
+func mirror[T any](ctx context.Context, cancel context.CancelFunc, conn *websocket.Conn, u string, next, limit int64, w *multichan.W[T]) {
+	var err error // the error that ended the mirroring, if any
+	defer cancel()
+	defer func() { w.CloseWithError(err) }()
+	defer func() { conn.CloseNow() }()
+
+	backoff := minBackoff
+	for {
+		var connErr error // the error from the connection
+		for {
+			var data []byte
+			data, connErr = read(ctx, conn, limit)
+			if websocket.CloseStatus(connErr) == websocket.StatusNormalClosure {
+				return
+			}
+			if connErr == errMessageTooBig {
+				conn.Close(websocket.StatusMessageTooBig, "message too big")
+				err = fmt.Errorf("message at offset %d exceeds limit of %d bytes", next, limit)
+				return
+			}
+			if connErr != nil {
+				break
+			}
+			var msg message[T]
+			if jerr := json.Unmarshal(data, &msg); jerr != nil {
+				conn.Close(websocket.StatusUnsupportedData, "invalid message")
+				err = fmt.Errorf("decoding message: %w", jerr)
+				return
+			}
+			if werr := w.WriteContext(ctx, msg.Val); werr != nil {
+				err = werr
+				return
+			}
+			next = msg.Offset + 1
+			backoff = minBackoff
+		}
+		conn.CloseNow()
+
+		for {
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+				if connErr != nil && !errors.Is(connErr, err) {
+					err = errors.Join(err, connErr)
+				}
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, maxBackoff)
+
+			var (
+				c     *websocket.Conn
+				start int64
+			)
+			c, start, connErr = dial(ctx, u, next, limit)
+			if connErr == ErrGap {
+				err = ErrGap
+				return
+			}
+			if connErr == nil {
+				conn, next = c, start
+				break
+			}
+		}
+	}
+}
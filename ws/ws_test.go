@@ -0,0 +1,199 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bobg/multichan"
+)
+
+func TestDial(t *testing.T) {
+	type point struct{ X, Y int }
+
+	w := multichan.New[point](multichan.Retain(10))
+	h := NewHandler(w, nil)
+
+	// Allow the test to break connections.
+	var (
+		mu      sync.Mutex
+		cancels []context.CancelFunc
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		mu.Lock()
+		cancels = append(cancels, cancel)
+		mu.Unlock()
+		h.ServeHTTP(rw, req.WithContext(ctx))
+	}))
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	r, err := Dial[point](ctx, "ws"+strings.TrimPrefix(s.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Dispose()
+
+	for w.NumReaders() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	w.WriteBatch([]point{{1, 2}, {3, 4}})
+	for i := 0; i < 2; i++ {
+		if _, ok := r.Read(ctx); !ok {
+			t.Fatal("read failed")
+		}
+	}
+
+	// Break the connection and write while the client is reconnecting.
+	mu.Lock()
+	cancels[0]()
+	mu.Unlock()
+	for w.NumReaders() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	w.Write(point{5, 6})
+	w.Close()
+
+	got, err := r.Drain(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []point{{5, 6}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(cancels) != 2 {
+		t.Errorf("got %d connections, want 2", len(cancels))
+	}
+}
+
+// breakableServer serves h,
+// returning the URL of the server
+// and a function that breaks the connections made so far.
+func breakableServer(t *testing.T, h http.Handler) (string, func()) {
+	var (
+		mu      sync.Mutex
+		cancels []context.CancelFunc
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		mu.Lock()
+		cancels = append(cancels, cancel)
+		mu.Unlock()
+		h.ServeHTTP(rw, req.WithContext(ctx))
+	}))
+	t.Cleanup(s.Close)
+
+	breakConns := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}
+	return "ws" + strings.TrimPrefix(s.URL, "http"), breakConns
+}
+
+// awaitReaders waits until w has n readers.
+func awaitReaders[T any](w *multichan.W[T], n int) {
+	for w.NumReaders() != n {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDialEarlyDisconnect(t *testing.T) {
+	w := multichan.New[int](multichan.Retain(10))
+	u, breakConns := breakableServer(t, NewHandler(w, nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	r, err := Dial[int](ctx, u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Dispose()
+
+	// Break the connection before any item is received,
+	// and write while the client is reconnecting.
+	awaitReaders(w, 1)
+	breakConns()
+	awaitReaders(w, 0)
+	w.Write(1)
+	w.Close()
+
+	got, err := r.Drain(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestDialGap(t *testing.T) {
+	w := multichan.New[int]()
+	u, breakConns := breakableServer(t, NewHandler(w, nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	r, err := Dial[int](ctx, u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Dispose()
+
+	awaitReaders(w, 1)
+	w.Write(1)
+	if _, ok := r.Read(ctx); !ok {
+		t.Fatal("read failed")
+	}
+
+	// Write while the client is reconnecting,
+	// with no reader to retain the item.
+	breakConns()
+	awaitReaders(w, 0)
+	w.Write(2)
+
+	if _, err := r.Drain(ctx); !errors.Is(err, ErrGap) {
+		t.Errorf("got error %v, want %v", err, ErrGap)
+	}
+}
+
+func TestDialReadLimit(t *testing.T) {
+	w := multichan.New[string]()
+	u, _ := breakableServer(t, NewHandler(w, nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	r, err := Dial[string](ctx, u, &DialOptions{ReadLimit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Dispose()
+
+	awaitReaders(w, 1)
+	w.Write("ok")
+	w.Write(strings.Repeat("x", 100))
+
+	got, err := r.Drain(ctx)
+	if err == nil || ctx.Err() != nil {
+		t.Errorf("got error %v, want a read-limit error", err)
+	}
+	if want := []string{"ok"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}